@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Roles de usuario permitidos
+const (
+	roleAdmin  = "admin"
+	roleMember = "member"
+)
+
+var allowedRoles = []string{roleAdmin, roleMember}
+
+type contextKey string
+
+// Clave de contexto con el usuario autenticado
+const callerKey contextKey = "caller"
+
+// La autenticación está activa cuando hay API keys configuradas
+func (srv *server) authEnabled() bool {
+	return len(srv.config.APIKeys) > 0
+}
+
+// Obtener el usuario autenticado de la petición
+func callerFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(callerKey).(User)
+	return user, ok
+}
+
+// Describir quién hace la petición para los logs, sin su API key: el ID del
+// usuario autenticado o, sin autenticación, la IP del cliente
+func (srv *server) callerLabel(r *http.Request) string {
+	if caller, ok := callerFromContext(r.Context()); ok {
+		return "user " + strconv.Itoa(caller.ID)
+	}
+	return "ip " + srv.ClientIP(r)
+}
+
+// Indica si quien hace la petición puede realizar operaciones de admin.
+// Sin autenticación configurada todas las peticiones lo son.
+func (srv *server) isAdmin(r *http.Request) bool {
+	if !srv.authEnabled() {
+		return true
+	}
+	caller, ok := callerFromContext(r.Context())
+	return ok && caller.Role == roleAdmin
+}
+
+// Buscar el usuario asociado a una API key
+func (srv *server) userForAPIKey(key string) (User, bool) {
+	id, ok := srv.config.APIKeys[key]
+	if !ok {
+		return User{}, false
+	}
+
+	srv.usersMu.RLock()
+	defer srv.usersMu.RUnlock()
+	if i := indexOfUser(srv.users, id); i >= 0 {
+		return srv.users[i], true
+	}
+	return User{}, false
+}
+
+// Middleware que exige una API key válida en X-API-Key para /api y /admin
+func (srv *server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !srv.authEnabled() || !(strings.HasPrefix(r.URL.Path, "/api/") || strings.HasPrefix(r.URL.Path, "/admin/")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		caller, ok := srv.userForAPIKey(r.Header.Get("X-API-Key"))
+		if !ok {
+			srv.writeError(w, r, http.StatusUnauthorized, codeUnauthorized, "A valid API key is required")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), callerKey, caller)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Restringir un handler a usuarios con rol admin
+func (srv *server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !srv.isAdmin(r) {
+			srv.writeError(w, r, http.StatusForbidden, codeForbidden, "Admin role required")
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/mail"
+)
+
+// Ruta de la comprobación de email, con su propio límite de peticiones
+const emailAvailablePath = "/api/users/email-available"
+
+// Comprobar si un email está libre para registrarse. Se normaliza y se
+// compara igual que al crear (sin distinguir mayúsculas y sin contar los
+// usuarios eliminados).
+func (srv *server) emailAvailableHandler(w http.ResponseWriter, r *http.Request) {
+	candidate := User{Email: r.URL.Query().Get("email")}
+	srv.normalizeUser(&candidate)
+	if candidate.Email == "" {
+		srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "The email parameter is required")
+		return
+	}
+	if addr, err := mail.ParseAddress(candidate.Email); err != nil || addr.Address != candidate.Email {
+		srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "Invalid email format")
+		return
+	}
+
+	srv.usersMu.RLock()
+	available := indexOfEmail(srv.users, candidate.Email, 0) < 0
+	srv.usersMu.RUnlock()
+
+	w.Header().Set("Cache-Control", "no-store")
+	srv.writeJSON(w, http.StatusOK, Response{
+		Status:  "success",
+		Message: "Email availability checked",
+		Data: map[string]interface{}{
+			"email":     candidate.Email,
+			"available": available,
+		},
+	})
+}
+
+// Handler de la ruta; con EMAIL_CHECK_RPS tiene su propio limitador en
+// lugar del general, porque los formularios lo llaman a cada pulsación
+func (srv *server) emailAvailableRoute() http.Handler {
+	var handler http.Handler = http.HandlerFunc(srv.emailAvailableHandler)
+	if srv.config.EmailCheckRPS > 0 {
+		handler = srv.rateLimitMiddleware(newRateLimiter(srv.config.EmailCheckRPS, srv.config.EmailCheckBurst, srv.stop))(handler)
+	}
+	return handler
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Extraer los valores de ids e id en el orden en que aparecen en la query;
+// url.Values no conserva el orden entre claves distintas
+func batchIDParams(rawQuery string) []string {
+	var parts []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		key, value, _ := strings.Cut(pair, "=")
+		if key != "ids" && key != "id" {
+			continue
+		}
+		value, err := url.QueryUnescape(value)
+		if err != nil || value == "" {
+			continue
+		}
+		if key == "ids" {
+			parts = append(parts, strings.Split(value, ",")...)
+		} else {
+			parts = append(parts, value)
+		}
+	}
+	return parts
+}
+
+// Obtener varios usuarios por ID en una sola llamada. Los IDs llegan como
+// ?ids=1,2,3, como ?id=1&id=2 o combinando ambas formas; se eliminan los
+// duplicados conservando el orden en que aparecen por primera vez.
+func (srv *server) getUsersBatchHandler(w http.ResponseWriter, r *http.Request) {
+	parts := batchIDParams(r.URL.RawQuery)
+	if len(parts) == 0 {
+		srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "The ids parameter is required")
+		return
+	}
+
+	ids := []int{}
+	seen := make(map[int]bool, len(parts))
+	for _, part := range parts {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id <= 0 {
+			srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "Invalid user ID %q", part)
+			return
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > srv.config.BatchMaxIDs {
+		srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "At most %d ids can be requested at once", srv.config.BatchMaxIDs)
+		return
+	}
+
+	// Con strict=true los IDs inexistentes aparecen como null en su posición
+	strict, _ := strconv.ParseBool(r.URL.Query().Get("strict"))
+
+	srv.usersMu.RLock()
+	defer srv.usersMu.RUnlock()
+
+	byID := make(map[int]User, len(srv.users))
+	for _, user := range srv.users {
+		if user.DeletedAt == nil {
+			byID[user.ID] = user
+		}
+	}
+
+	result := []*User{}
+	for _, id := range ids {
+		user, ok := byID[id]
+		switch {
+		case ok:
+			result = append(result, &user)
+		case strict:
+			result = append(result, nil)
+		}
+	}
+
+	if srv.clientGone(r) {
+		return
+	}
+	srv.writeJSON(w, http.StatusOK, Response{
+		Status:  "success",
+		Message: "Users retrieved successfully",
+		Data:    result,
+	})
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// El circuito está abierto y no se intentó la escritura
+var errCircuitOpen = errors.New("circuit breaker is open")
+
+// Estados del circuit breaker
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+// Circuit breaker para las escrituras al store: se abre tras threshold
+// fallos consecutivos y rechaza sin intentar durante cooldown; después deja
+// pasar una sola escritura de prueba (half-open) que lo cierra o lo reabre
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     string
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, state: breakerClosed}
+}
+
+// Decidir si se puede intentar una operación
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return errCircuitOpen
+		}
+		b.transition(breakerHalfOpen)
+		b.probing = true
+		return nil
+	case breakerHalfOpen:
+		if b.probing {
+			return errCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// Tiempo que falta para que el circuito abierto deje pasar una prueba
+func (b *circuitBreaker) remainingCooldown() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != breakerOpen {
+		return 0
+	}
+	return b.cooldown - time.Since(b.openedAt)
+}
+
+// Registrar el resultado de una operación permitida por allow
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if err == nil {
+		b.failures = 0
+		if b.state != breakerClosed {
+			b.transition(breakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.openedAt = time.Now()
+		if b.state != breakerOpen {
+			b.transition(breakerOpen)
+		}
+	}
+}
+
+// Cambiar de estado dejando constancia en el log. Debe llamarse con mu bloqueado.
+func (b *circuitBreaker) transition(state string) {
+	log.Printf("Store circuit breaker %s -> %s (%d consecutive failures)", b.state, state, b.failures)
+	b.state = state
+}
+
+// Guardar a través del circuit breaker si está activo, reintentando los
+// errores transitorios (STORE_RETRY_ATTEMPTS) con una espera total de como
+// mucho budget; los reintentos cuentan como un fallo para el breaker. Con
+// usersMu bloqueado budget debe ser lockedRetryBudget.
+func (srv *server) saveSnapshot(snapshot storeData, budget time.Duration) error {
+	save := func() error {
+		return srv.withRetry("save", srv.config.StoreRetryAttempts, budget, func() error { return srv.store.Save(snapshot) })
+	}
+	if srv.storeBreaker == nil {
+		return save()
+	}
+	if err := srv.storeBreaker.allow(); err != nil {
+		return err
+	}
+	err := save()
+	if errors.Is(err, errExternalChange) {
+		// El archivo responde; la escritura se rechazó a propósito
+		srv.storeBreaker.record(nil)
+		return err
+	}
+	srv.storeBreaker.record(err)
+	return err
+}
+
+// Diagnóstico que acompaña al 503 de una escritura rechazada por el circuito
+// abierto. Las lecturas se sirven desde memoria y no pasan por el breaker.
+type circuitOpenDetails struct {
+	Reason              string    `json:"reason"`
+	RetryAfterSeconds   int       `json:"retry_after_seconds"`
+	EstimatedRecoveryAt time.Time `json:"estimated_recovery_at"`
+	ReadsAvailable      bool      `json:"reads_available"`
+	RequestID           string    `json:"request_id,omitempty"`
+}
+
+// Responder 503 a una escritura rechazada por el circuito abierto
+func (srv *server) writeCircuitOpen(w http.ResponseWriter, r *http.Request) {
+	remaining := srv.storeBreaker.remainingCooldown()
+	setRetryAfter(w, remaining)
+	w.Header().Set("Content-Language", requestLanguage(r))
+	w.Header().Add("Vary", "Accept-Language")
+	srv.writeJSON(w, http.StatusServiceUnavailable, Response{
+		Status:  "error",
+		Message: localize(r, "Data store is temporarily unavailable, changes were not saved"),
+		Code:    codeStoreUnavailable,
+		Data: circuitOpenDetails{
+			Reason:              "circuit_open",
+			RetryAfterSeconds:   max(int(math.Ceil(remaining.Seconds())), 1),
+			EstimatedRecoveryAt: time.Now().Add(remaining).UTC().Truncate(time.Second),
+			ReadsAvailable:      true,
+			RequestID:           requestIDFromContext(r.Context()),
+		},
+	})
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"runtime/debug"
+)
+
+// Metadatos de la compilación, inyectados con
+// -ldflags "-X main.buildVersion=... -X main.buildCommit=... -X main.buildTime=..."
+var (
+	buildVersion = "dev"
+	buildCommit  = ""
+	buildTime    = ""
+)
+
+// Completar commit y fecha con la información VCS que go build incrusta
+// cuando no se pasaron por ldflags
+func init() {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	for _, setting := range info.Settings {
+		switch {
+		case setting.Key == "vcs.revision" && buildCommit == "":
+			buildCommit = setting.Value
+		case setting.Key == "vcs.time" && buildTime == "":
+			buildTime = setting.Value
+		}
+	}
+}
+
+// Metadatos de la compilación para /health
+func buildInfo() map[string]string {
+	return map[string]string{
+		"version":    buildVersion,
+		"commit":     buildCommit,
+		"build_time": buildTime,
+	}
+}
+
+// Middleware que identifica la versión que sirvió cada respuesta
+func buildVersionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Build-Version", buildVersion)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// Modos de las operaciones bulk: atomic aplica todo o nada; best_effort
+// procesa cada elemento por separado y responde 207 con el resultado de cada uno
+const (
+	bulkAtomic     = "atomic"
+	bulkBestEffort = "best_effort"
+)
+
+// Resultado de un elemento en modo best_effort
+type bulkItemResult struct {
+	Index   int    `json:"index"`
+	Status  int    `json:"status"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Con Prefer: return=minimal solo se devuelve el ID del usuario creado
+	ID   int   `json:"id,omitempty"`
+	User *User `json:"user,omitempty"`
+}
+
+// Leer ?mode; por seguridad el modo por defecto es atomic
+func (srv *server) parseBulkMode(w http.ResponseWriter, r *http.Request) (string, bool) {
+	switch mode := r.URL.Query().Get("mode"); mode {
+	case "", bulkAtomic:
+		return bulkAtomic, true
+	case bulkBestEffort:
+		return bulkBestEffort, true
+	default:
+		srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "Invalid mode: must be atomic or best_effort")
+		return "", false
+	}
+}
+
+// Leer el lote de una actualización bulk; debe tener al menos un usuario.
+// Devuelve también los campos presentes en cada elemento, para comprobar
+// los inmutables.
+func (srv *server) decodeBulkBody(w http.ResponseWriter, r *http.Request) ([]User, []map[string]json.RawMessage, bool) {
+	body, ok := srv.readJSONBody(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	var batch []User
+	var fields []map[string]json.RawMessage
+	if !srv.unmarshalJSONBody(w, r, body, &batch) || !srv.unmarshalJSONBody(w, r, body, &fields) {
+		return nil, nil, false
+	}
+	if len(batch) == 0 {
+		srv.writeError(w, r, http.StatusUnprocessableEntity, codeValidationFailed, "At least one user is required")
+		return nil, nil, false
+	}
+	for i := range batch {
+		srv.normalizeUser(&batch[i])
+	}
+	return batch, fields, true
+}
+
+// Error de un elemento que intenta cambiar un campo inmutable, igual que en
+// PUT y PATCH de un solo usuario
+func immutableFieldItem(index int, fields map[string]json.RawMessage, current User) *apiError {
+	if field := checkImmutableFields(fields, current); field != "" {
+		return newAPIError(http.StatusBadRequest, codeImmutableField, "Record %d: field %q is immutable and cannot be modified", index, field)
+	}
+	return nil
+}
+
+// Resultado fallido de un elemento, con el mensaje ya traducido
+func failedItem(r *http.Request, index int, err *apiError) bulkItemResult {
+	return bulkItemResult{Index: index, Status: err.status, Code: err.code, Message: localize(r, err.format, err.args...)}
+}
+
+// Responder 207 con el resultado de cada elemento
+func (srv *server) writeBulkResults(w http.ResponseWriter, results []bulkItemResult) {
+	failed := 0
+	for _, result := range results {
+		if result.Status >= 400 {
+			failed++
+		}
+	}
+	srv.writeJSON(w, http.StatusMultiStatus, Response{
+		Status:  "success",
+		Message: fmt.Sprintf("%d succeeded, %d failed", len(results)-failed, failed),
+		Data:    map[string]interface{}{"results": results},
+	})
+}
+
+// Validar un elemento de una actualización bulk
+func (srv *server) validateBulkUpdate(index int, user User, seen map[int]bool) *apiError {
+	if user.ID <= 0 {
+		return newAPIError(http.StatusUnprocessableEntity, codeValidationFailed, "Record %d: a valid user ID is required", index)
+	}
+	if seen[user.ID] {
+		return newAPIError(http.StatusUnprocessableEntity, codeValidationFailed, "Record %d: duplicate user ID %d", index, user.ID)
+	}
+	seen[user.ID] = true
+	if msg := srv.validateUser(user); msg != "" {
+		return newAPIError(http.StatusUnprocessableEntity, codeValidationFailed, "Record %d: %s", index, messageKey(msg))
+	}
+	return nil
+}
+
+// Sustituir en next el usuario existente por la versión del lote
+func applyBulkUpdate(next []User, i int, user User, now time.Time) User {
+	user.CreatedAt = next[i].CreatedAt
+	user.UpdatedAt = now
+	user.DeletedAt = nil
+	keepVerification(&user, next[i])
+	next[i] = user
+	return user
+}
+
+// Actualizar varios usuarios en una sola operación (?mode=atomic|best_effort)
+func (srv *server) bulkUpdateUsersHandler(w http.ResponseWriter, r *http.Request) {
+	mode, ok := srv.parseBulkMode(w, r)
+	if !ok {
+		return
+	}
+	batch, fields, ok := srv.decodeBulkBody(w, r)
+	if !ok {
+		return
+	}
+	if mode == bulkBestEffort {
+		srv.bulkUpdateBestEffort(w, r, batch, fields)
+		return
+	}
+
+	// Validar todo el lote antes de aplicar cualquier cambio
+	seen := make(map[int]bool, len(batch))
+	for i, user := range batch {
+		if err := srv.validateBulkUpdate(i, user, seen); err != nil {
+			srv.writeAPIError(w, r, err)
+			return
+		}
+	}
+
+	srv.usersMu.Lock()
+	defer srv.usersMu.Unlock()
+
+	now := time.Now().UTC()
+	next := slices.Clone(srv.users)
+	updated := []User{}
+	notFound := []int{}
+	for index, user := range batch {
+		i := indexOfUser(next, user.ID)
+		if i < 0 {
+			notFound = append(notFound, user.ID)
+			continue
+		}
+		if err := immutableFieldItem(index, fields[index], next[i]); err != nil {
+			srv.writeAPIError(w, r, err)
+			return
+		}
+		updated = append(updated, applyBulkUpdate(next, i, user, now))
+	}
+
+	// Los campos únicos deben seguir siéndolo con todo el lote aplicado
+	for i, user := range batch {
+		if indexOfUser(next, user.ID) < 0 {
+			continue
+		}
+		if field := srv.findUniqueConflict(next, user, user.ID); field != "" {
+			srv.writeAPIError(w, r, uniqueConflictItem(i, field, user))
+			return
+		}
+	}
+
+	if len(updated) > 0 && !srv.commitUsers(w, r, next) {
+		return
+	}
+
+	srv.writeJSON(w, http.StatusOK, Response{
+		Status:  "success",
+		Message: fmt.Sprintf("%d users updated, %d not found", len(updated), len(notFound)),
+		Data: map[string]interface{}{
+			"updated":   updated,
+			"not_found": notFound,
+		},
+	})
+}
+
+// Actualización bulk en modo best_effort
+func (srv *server) bulkUpdateBestEffort(w http.ResponseWriter, r *http.Request, batch []User, fields []map[string]json.RawMessage) {
+	srv.usersMu.Lock()
+	defer srv.usersMu.Unlock()
+
+	now := time.Now().UTC()
+	next := slices.Clone(srv.users)
+	seen := make(map[int]bool, len(batch))
+	results := make([]bulkItemResult, 0, len(batch))
+	changed := false
+	for index, user := range batch {
+		if err := srv.validateBulkUpdate(index, user, seen); err != nil {
+			results = append(results, failedItem(r, index, err))
+			continue
+		}
+		i := indexOfUser(next, user.ID)
+		if i < 0 {
+			results = append(results, failedItem(r, index, newAPIError(http.StatusNotFound, codeNotFound, "Record %d: user not found", index)))
+			continue
+		}
+		if err := immutableFieldItem(index, fields[index], next[i]); err != nil {
+			results = append(results, failedItem(r, index, err))
+			continue
+		}
+		if field := srv.findUniqueConflict(next, user, user.ID); field != "" {
+			results = append(results, failedItem(r, index, uniqueConflictItem(index, field, user)))
+			continue
+		}
+		updated := applyBulkUpdate(next, i, user, now)
+		results = append(results, bulkItemResult{Index: index, Status: http.StatusOK, User: &updated})
+		changed = true
+	}
+
+	if changed && !srv.commitUsers(w, r, next) {
+		return
+	}
+	srv.writeBulkResults(w, results)
+}
+
+// Preparar un usuario del lote para añadirlo; asigna su ID
+func (srv *server) newBulkUser(user User, now time.Time) User {
+	srv.applyUserDefaults(&user)
+	user.ID = srv.idGenerator.Next()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	user.DeletedAt = nil
+	user.EmailVerified = false
+	return user
+}
+
+// Elemento de una creación bulk ya decodificado; err es el fallo de su
+// validación, si lo hubo
+type bulkCandidate struct {
+	user User
+	err  *apiError
+}
+
+// Crear varios usuarios en una sola operación (?mode=atomic|best_effort).
+// El cuerpo se decodifica en streaming y cada elemento se valida al leerlo,
+// sin bloquear usersMu; en modo atomic el primer elemento inválido corta la
+// lectura. Los emails se comprueban frente a los usuarios existentes al final.
+func (srv *server) bulkCreateUsersHandler(w http.ResponseWriter, r *http.Request) {
+	mode, ok := srv.parseBulkMode(w, r)
+	if !ok {
+		return
+	}
+	stream, ok := srv.openBulkStream(w, r)
+	if !ok {
+		return
+	}
+
+	var candidates []bulkCandidate
+	seen := uniqueIndex{}
+	failed := false
+	_, ok = stream.each(w, r, func(index int, user User) bool {
+		var err *apiError
+		if msg := srv.validateUser(user); msg != "" {
+			err = newAPIError(http.StatusUnprocessableEntity, codeValidationFailed, "Record %d: %s", index, messageKey(msg))
+		} else if field := seen.add(srv.config.UniqueFields, user); field != "" {
+			err = uniqueConflictItem(index, field, user)
+		}
+		if err != nil && mode == bulkAtomic {
+			srv.writeAPIError(w, r, err)
+			failed = true
+			return false
+		}
+		candidates = append(candidates, bulkCandidate{user: user, err: err})
+		return true
+	})
+	if !ok || failed {
+		return
+	}
+
+	srv.usersMu.Lock()
+	defer srv.usersMu.Unlock()
+
+	now := time.Now().UTC()
+	next := slices.Clone(srv.users)
+
+	// Con Prefer: return=minimal la respuesta lleva los IDs creados en lugar
+	// de los usuarios completos
+	minimal := preferredReturn(r) == preferMinimal
+	w.Header().Add("Vary", "Prefer")
+	if minimal {
+		w.Header().Set("Preference-Applied", "return="+preferMinimal)
+	}
+
+	if mode == bulkBestEffort {
+		results := make([]bulkItemResult, 0, len(candidates))
+		changed := false
+		for index, candidate := range candidates {
+			err := candidate.err
+			// Los choques dentro del lote ya los detectó seen, así que basta
+			// con comparar con los usuarios que ya existían (y no con next,
+			// que crece con el lote y haría la comprobación cuadrática)
+			if err == nil {
+				if field := srv.findUniqueConflict(srv.users, candidate.user, 0); field != "" {
+					err = uniqueConflictItem(index, field, candidate.user)
+				}
+			}
+			if err != nil {
+				results = append(results, failedItem(r, index, err))
+				continue
+			}
+			created := srv.newBulkUser(candidate.user, now)
+			next = append(next, created)
+			result := bulkItemResult{Index: index, Status: http.StatusCreated, ID: created.ID}
+			if !minimal {
+				result.User = &created
+			}
+			results = append(results, result)
+			changed = true
+		}
+		if changed && !srv.commitUsers(w, r, next) {
+			return
+		}
+		srv.writeBulkResults(w, results)
+		return
+	}
+
+	// Comprobar todo el lote antes de asignar ningún ID
+	for index, candidate := range candidates {
+		if field := srv.findUniqueConflict(next, candidate.user, 0); field != "" {
+			srv.writeAPIError(w, r, uniqueConflictItem(index, field, candidate.user))
+			return
+		}
+	}
+
+	created := make([]User, len(candidates))
+	for i, candidate := range candidates {
+		created[i] = srv.newBulkUser(candidate.user, now)
+	}
+	if !srv.commitUsers(w, r, append(next, created...)) {
+		return
+	}
+
+	if minimal {
+		ids := make([]int, len(created))
+		for i, user := range created {
+			ids[i] = user.ID
+		}
+		srv.writeJSON(w, http.StatusCreated, Response{
+			Status:  "success",
+			Message: fmt.Sprintf("%d users created", len(created)),
+			Data: map[string]interface{}{
+				"count":             len(created),
+				"ids":               ids,
+				"location_template": "/api/users/{id}",
+			},
+		})
+		return
+	}
+	srv.writeJSON(w, http.StatusCreated, Response{
+		Status:  "success",
+		Message: fmt.Sprintf("%d users created", len(created)),
+		Data:    map[string]interface{}{"created": created},
+	})
+}
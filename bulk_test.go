@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBulkUpdateRejectsImmutableFields(t *testing.T) {
+	srv, ts := newTestServer(t, memoryStore{}, nil)
+	created := srv.users[0].CreatedAt.Format(time.RFC3339Nano)
+
+	// atomic: igual que PUT /api/users/{id}, el lote entero se rechaza
+	body := `[{"id":1,"name":"Juan Pérez","email":"juan@example.com","created_at":"2000-01-01T00:00:00Z"}]`
+	resp, data := doRequest(t, ts, "PUT", "/api/users/bulk", body)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("atomic: status %d, want 400: %s", resp.StatusCode, data)
+	}
+	if got := decodeResponse(t, data).Code; got != codeImmutableField {
+		t.Errorf("atomic: code %q, want %q", got, codeImmutableField)
+	}
+	if got := srv.users[0].CreatedAt.Format(time.RFC3339Nano); got != created {
+		t.Errorf("created_at changed to %s", got)
+	}
+
+	// Repetir el valor actual está permitido
+	body = fmt.Sprintf(`[{"id":1,"name":"Juan P.","email":"juan@example.com","created_at":%q}]`, created)
+	if resp, data := doRequest(t, ts, "PUT", "/api/users/bulk", body); resp.StatusCode != http.StatusOK {
+		t.Errorf("atomic with the current created_at: status %d, want 200: %s", resp.StatusCode, data)
+	}
+
+	// best_effort: solo falla el elemento que cambia el campo
+	body = `[{"id":1,"name":"Juan Pérez","email":"juan@example.com","deleted_at":"2000-01-01T00:00:00Z"},
+		{"id":2,"name":"María G.","email":"maria@example.com"}]`
+	resp, data = doRequest(t, ts, "PUT", "/api/users/bulk?mode=best_effort", body)
+	if resp.StatusCode != http.StatusMultiStatus {
+		t.Fatalf("best_effort: status %d, want 207: %s", resp.StatusCode, data)
+	}
+	var results struct {
+		Data struct {
+			Results []bulkItemResult `json:"results"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &results); err != nil {
+		t.Fatal(err)
+	}
+	if got := results.Data.Results; len(got) != 2 || got[0].Status != http.StatusBadRequest || got[0].Code != codeImmutableField || got[1].Status != http.StatusOK {
+		t.Errorf("best_effort results %+v, want item 0 rejected as immutable and item 1 updated", got)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"unicode/utf8"
+)
+
+// El cuerpo leído en streaming contiene bytes que no son UTF-8
+var errInvalidUTF8 = errors.New("request body is not valid UTF-8")
+
+// Rutas cuyo cuerpo se decodifica en streaming: no se lee entero en memoria
+// para validar el UTF-8 y admiten hasta BULK_MAX_BODY_BYTES
+func streamsBody(r *http.Request) bool {
+	template, ok := routeTemplate(r)
+	return ok && r.Method == "POST" && template == "/api/users/bulk"
+}
+
+// Lector que comprueba el UTF-8 a medida que se lee; guarda los bytes de
+// una runa partida entre dos lecturas hasta completarla
+type utf8Reader struct {
+	body io.ReadCloser
+	tail []byte
+}
+
+func (u *utf8Reader) Read(p []byte) (int, error) {
+	n, err := u.body.Read(p)
+	data := append(u.tail, p[:n]...)
+
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-(utf8.UTFMax-1); i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+	if !utf8.Valid(data[:cut]) || (err == io.EOF && cut < len(data)) {
+		return n, errInvalidUTF8
+	}
+	u.tail = append(u.tail[:0], data[cut:]...)
+	return n, err
+}
+
+func (u *utf8Reader) Close() error { return u.body.Close() }
+
+// Decodificador de un array JSON de usuarios elemento a elemento, para que
+// los lotes grandes no se carguen enteros en memoria
+type bulkStream struct {
+	srv *server
+	dec *json.Decoder
+}
+
+// Empezar a leer el lote: el cuerpo debe ser un array JSON
+func (srv *server) openBulkStream(w http.ResponseWriter, r *http.Request) (*bulkStream, bool) {
+	body := bufio.NewReader(r.Body)
+	// Algunas herramientas de Windows anteponen un BOM UTF-8
+	if prefix, _ := body.Peek(len(utf8BOM)); bytes.Equal(prefix, utf8BOM) {
+		body.Discard(len(utf8BOM))
+	}
+
+	s := &bulkStream{srv: srv, dec: json.NewDecoder(body)}
+	tok, err := s.dec.Token()
+	if err != nil {
+		s.fail(w, r, err)
+		return nil, false
+	}
+	if tok != json.Delim('[') {
+		srv.writeRootTypeError(w, r, "array", tokenKind(tok))
+		return nil, false
+	}
+	return s, true
+}
+
+// Tipo JSON del primer token de un documento
+func tokenKind(tok json.Token) string {
+	switch tok.(type) {
+	case json.Delim:
+		return "object"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return "number"
+}
+
+// Decodificar cada elemento, normalizado, y pasárselo a fn junto con su
+// índice hasta que fn devuelva false o se acabe el array. Cada elemento pasa
+// las mismas comprobaciones que un cuerpo completo (anidamiento, claves
+// repetidas). Devuelve cuántos elementos se leyeron; si hubo un error de
+// lectura o de sintaxis ya se respondió y ok es false.
+func (s *bulkStream) each(w http.ResponseWriter, r *http.Request, fn func(index int, user User) bool) (count int, ok bool) {
+	for s.dec.More() {
+		var raw json.RawMessage
+		if err := s.dec.Decode(&raw); err != nil {
+			s.fail(w, r, err)
+			return count, false
+		}
+		// El array ya ocupa un nivel de anidamiento
+		if exceedsJSONDepth(raw, s.srv.config.MaxJSONDepth-1) {
+			s.srv.writeError(w, r, http.StatusBadRequest, codeInvalidJSON, "JSON nesting exceeds the maximum depth of %d", s.srv.config.MaxJSONDepth)
+			return count, false
+		}
+		if key, _ := findDuplicateKey(raw); key != "" {
+			s.srv.writeError(w, r, http.StatusBadRequest, codeInvalidJSON, "Duplicate JSON key %q", key)
+			return count, false
+		}
+		var user User
+		if err := json.Unmarshal(raw, &user); err != nil {
+			s.srv.writeError(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid JSON format")
+			return count, false
+		}
+		s.srv.normalizeUser(&user)
+
+		count++
+		if !fn(count-1, user) {
+			return count, true
+		}
+	}
+
+	// Cierre del array y nada más detrás
+	if _, err := s.dec.Token(); err != nil {
+		s.fail(w, r, err)
+		return count, false
+	}
+	// Como en hasTrailingData, pero un error de lectura (p. ej. UTF-8
+	// inválido detectado en el último bloque) no es contenido sobrante
+	if _, err := s.dec.Token(); err != io.EOF {
+		var syntax *json.SyntaxError
+		if err != nil && !errors.As(err, &syntax) {
+			s.srv.writeBodyReadError(w, r, err)
+			return count, false
+		}
+		s.srv.writeError(w, r, http.StatusBadRequest, codeInvalidJSON, "Request body must contain a single JSON object")
+		return count, false
+	}
+	if count == 0 {
+		s.srv.writeError(w, r, http.StatusUnprocessableEntity, codeValidationFailed, "At least one user is required")
+		return count, false
+	}
+	return count, true
+}
+
+// Responder a un error del decodificador: los de lectura del cuerpo se
+// tratan como en el resto de handlers y los demás son JSON inválido
+func (s *bulkStream) fail(w http.ResponseWriter, r *http.Request, err error) {
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		s.srv.writeError(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid JSON format")
+		return
+	}
+	s.srv.writeBodyReadError(w, r, err)
+}
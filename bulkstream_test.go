@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// Tamaño del lote sintético: con ~60 bytes por usuario supera MAX_BODY_BYTES
+// (1 MiB), el límite de un cuerpo que se decodifica entero
+const syntheticBulkSize = 20000
+
+// Enviar un lote sintético generado mientras se envía, sin Content-Length;
+// invalid es el índice de un usuario sin email o -1
+func postSyntheticBulk(t *testing.T, url string, count, invalid int) (*http.Response, []byte) {
+	t.Helper()
+	body, writer := io.Pipe()
+	go func() {
+		io.WriteString(writer, "[")
+		for i := 0; i < count; i++ {
+			if i > 0 {
+				io.WriteString(writer, ",")
+			}
+			if i == invalid {
+				fmt.Fprintf(writer, `{"name":"User %d"}`, i)
+				continue
+			}
+			fmt.Fprintf(writer, `{"name":"User %d","email":"user%d@example.com"}`, i, i)
+		}
+		io.WriteString(writer, "]")
+		writer.Close()
+	}()
+
+	req, err := http.NewRequest("POST", url, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Prefer", "return=minimal")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, data
+}
+
+func TestBulkCreateStreamsLargePayload(t *testing.T) {
+	srv, ts := newTestServer(t, memoryStore{}, nil)
+
+	resp, data := postSyntheticBulk(t, ts.URL+"/api/users/bulk", syntheticBulkSize, -1)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("status %d, want 201: %.300s", resp.StatusCode, data)
+	}
+	var created struct {
+		Data struct {
+			Count int   `json:"count"`
+			IDs   []int `json:"ids"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Data.Count != syntheticBulkSize || len(created.Data.IDs) != syntheticBulkSize {
+		t.Errorf("created %d users (%d IDs), want %d", created.Data.Count, len(created.Data.IDs), syntheticBulkSize)
+	}
+	if got := len(srv.users); got != syntheticBulkSize+2 {
+		t.Errorf("server has %d users, want %d", got, syntheticBulkSize+2)
+	}
+}
+
+func TestBulkCreateStreamingKeepsModes(t *testing.T) {
+	invalid := syntheticBulkSize / 2
+
+	// atomic: un error descarta el lote entero
+	srv, ts := newTestServer(t, memoryStore{}, nil)
+	resp, data := postSyntheticBulk(t, ts.URL+"/api/users/bulk", syntheticBulkSize, invalid)
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("atomic: status %d, want 422: %.300s", resp.StatusCode, data)
+	}
+	if got := len(srv.users); got != 2 {
+		t.Errorf("atomic: server has %d users after a failed batch, want 2", got)
+	}
+
+	// best_effort: se crean todos menos el inválido
+	srv, ts = newTestServer(t, memoryStore{}, nil)
+	resp, data = postSyntheticBulk(t, ts.URL+"/api/users/bulk?mode=best_effort", syntheticBulkSize, invalid)
+	if resp.StatusCode != http.StatusMultiStatus {
+		t.Fatalf("best_effort: status %d, want 207: %.300s", resp.StatusCode, data)
+	}
+	var results struct {
+		Data struct {
+			Results []bulkItemResult `json:"results"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &results); err != nil {
+		t.Fatal(err)
+	}
+	failed := 0
+	for _, result := range results.Data.Results {
+		if result.Status != http.StatusCreated {
+			failed++
+			if result.Index != invalid {
+				t.Errorf("best_effort: item %d failed with %d, want only item %d to fail", result.Index, result.Status, invalid)
+			}
+		}
+	}
+	if failed != 1 || len(results.Data.Results) != syntheticBulkSize {
+		t.Errorf("best_effort: %d results with %d failures, want %d with 1", len(results.Data.Results), failed, syntheticBulkSize)
+	}
+	if got := len(srv.users); got != syntheticBulkSize+1 {
+		t.Errorf("best_effort: server has %d users, want %d", got, syntheticBulkSize+1)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Respuesta serializada guardada en caché
+type cachedResponse struct {
+	key     string
+	version uint64
+	expires time.Time
+	status  int
+	header  http.Header
+	body    []byte
+}
+
+// Caché LRU de respuestas con caducidad
+type responseCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+	// Cabeceras de Vary vistas en las respuestas de cada ruta, que pasan a
+	// formar parte de la clave
+	vary map[string][]string
+	// Versión de los datos del servidor; una entrada de otra versión caduca
+	version *atomic.Uint64
+}
+
+func newResponseCache(ttl time.Duration, capacity int, version *atomic.Uint64) *responseCache {
+	return &responseCache{
+		version:  version,
+		ttl:      ttl,
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		vary:     make(map[string][]string),
+	}
+}
+
+// Cabeceras de petición que siempre cambian la representación, aunque la
+// ruta todavía no haya respondido con su Vary
+var defaultCacheVary = []string{"Accept", "Accept-Encoding", "Accept-Language", "X-Feature-Flags"}
+
+// Rutas que responden en streaming: no se guardan, así que tampoco se copia
+// su respuesta
+var uncachedRoutes = map[string]bool{
+	"/api/users/export": true,
+}
+
+// Cabeceras que forman la clave de una ruta: las de defaultCacheVary más
+// las que han aparecido en el Vary de sus respuestas
+func (c *responseCache) varyFor(route string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if vary, ok := c.vary[route]; ok {
+		return vary
+	}
+	return defaultCacheVary
+}
+
+// Añadir a la ruta las cabeceras del Vary de una respuesta; devuelve false
+// con Vary: *, que no se puede guardar en caché
+func (c *responseCache) learnVary(route string, header http.Header) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	vary, ok := c.vary[route]
+	if !ok {
+		vary = slices.Clone(defaultCacheVary)
+	}
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "*" {
+				return false
+			}
+			if name != "" && !slices.Contains(vary, name) {
+				vary = append(vary, name)
+			}
+		}
+	}
+	c.vary[route] = vary
+	return true
+}
+
+func (c *responseCache) get(key string) (*cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*cachedResponse)
+	if entry.version != c.version.Load() || time.Now().After(entry.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return entry, true
+}
+
+func (c *responseCache) put(entry *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[entry.key]; ok {
+		c.order.Remove(el)
+	}
+	c.entries[entry.key] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedResponse).key)
+	}
+}
+
+// Clave de caché: ruta, query, quién llama (/api/users/me depende de ello),
+// la URL base pública (los enlaces de _links y Link dependen de Host y de
+// X-Forwarded-Proto/Host/Prefix) y las cabeceras de vary
+func (srv *server) cacheKey(r *http.Request, vary []string) string {
+	caller := ""
+	if user, ok := callerFromContext(r.Context()); ok {
+		caller = strconv.Itoa(user.ID)
+	}
+	parts := []string{r.URL.Path, caller, r.URL.RawQuery, srv.requestBaseURL(r)}
+	for _, name := range vary {
+		parts = append(parts, name+":"+strings.Join(r.Header.Values(name), ","))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// ResponseWriter que copia la respuesta para poder guardarla
+type recordingWriter struct {
+	http.ResponseWriter
+	status  int
+	body    bytes.Buffer
+	flushed bool
+}
+
+func (rw *recordingWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	if !rw.flushed {
+		rw.body.Write(b)
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+// Las respuestas en streaming no se guardan en caché: tras el primer Flush
+// se deja de copiar el cuerpo y se libera lo copiado
+func (rw *recordingWriter) Flush() {
+	if !rw.flushed {
+		rw.flushed = true
+		rw.body = bytes.Buffer{}
+	}
+	http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+func (rw *recordingWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Middleware que sirve desde caché las respuestas GET de /api
+func (srv *server) responseCacheMiddleware(cache *responseCache) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cacheable := r.Method == "GET" &&
+				strings.HasPrefix(r.URL.Path, "/api/") &&
+				r.Header.Get("If-Modified-Since") == "" &&
+				r.Header.Get("If-None-Match") == ""
+			route, _ := routeTemplate(r)
+			if !cacheable || uncachedRoutes[route] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := srv.cacheKey(r, cache.varyFor(route))
+			if entry, ok := cache.get(key); ok {
+				for name, values := range entry.header {
+					w.Header()[name] = values
+				}
+				w.Header().Set("X-Cache", "HIT")
+				w.WriteHeader(entry.status)
+				w.Write(entry.body)
+				return
+			}
+
+			version := srv.dataVersion.Load()
+			w.Header().Set("X-Cache", "MISS")
+			before := w.Header().Clone()
+			rw := &recordingWriter{ResponseWriter: w}
+			next.ServeHTTP(rw, r)
+
+			if rw.status == http.StatusOK && !rw.flushed && cache.learnVary(route, w.Header()) {
+				// Si la respuesta añadió cabeceras de Vary nuevas la clave cambia
+				key = srv.cacheKey(r, cache.varyFor(route))
+				// Guardar solo las cabeceras de la respuesta, no las que ya
+				// fijaron los middlewares externos (límite de peticiones, etc.)
+				header := http.Header{}
+				for name, values := range w.Header() {
+					if !slices.Equal(before[name], values) {
+						header[name] = slices.Clone(values)
+					}
+				}
+				cache.put(&cachedResponse{
+					key:     key,
+					version: version,
+					expires: time.Now().Add(cache.ttl),
+					status:  rw.status,
+					header:  header,
+					body:    rw.body.Bytes(),
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRecordingWriterStopsBufferingAfterFlush(t *testing.T) {
+	out := httptest.NewRecorder()
+	rw := &recordingWriter{ResponseWriter: out}
+
+	rw.Write([]byte("first batch\n"))
+	rw.Flush()
+	rw.Write([]byte("second batch\n"))
+	rw.Flush()
+
+	if rw.body.Len() != 0 || rw.body.Cap() != 0 {
+		t.Errorf("recorded body holds %d bytes (capacity %d) after Flush, want it released", rw.body.Len(), rw.body.Cap())
+	}
+	if got := out.Body.String(); got != "first batch\nsecond batch\n" {
+		t.Errorf("client got %q, want every write passed through", got)
+	}
+}
+
+func TestResponseCacheSkipsStreamingRoutes(t *testing.T) {
+	_, ts := newTestServer(t, memoryStore{}, func(cfg *Config) {
+		cfg.ResponseCacheTTL = time.Minute
+	})
+
+	for i := 0; i < 2; i++ {
+		resp, data := doRequest(t, ts, "GET", "/api/users/export", "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("export: status %d: %s", resp.StatusCode, data)
+		}
+		if got := resp.Header.Get("X-Cache"); got != "" {
+			t.Errorf("export %d: X-Cache %q, want the streaming route left out of the cache", i+1, got)
+		}
+	}
+
+	// El listado sí se sirve desde caché
+	doRequest(t, ts, "GET", "/api/users", "")
+	if resp, _ := doRequest(t, ts, "GET", "/api/users", ""); resp.Header.Get("X-Cache") != "HIT" {
+		t.Errorf("second GET /api/users: X-Cache %q, want HIT", resp.Header.Get("X-Cache"))
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+)
+
+// Etapas de la cadena de middlewares, de fuera hacia dentro. La cadena se
+// ordena siempre por etapa, así que el orden en que se añaden no importa:
+// recovery queda por fuera de todos (atrapa también los panics de auth o
+// del límite de peticiones), el request ID existe antes de escribir el log
+// y el log envuelve a auth para registrar también los 401. El límite de
+// peticiones va antes de auth para frenar también a quien prueba API keys.
+const (
+	stageRecovery = iota
+	stageCorrelation
+	stageLogging
+	stageHeaders
+	stageRateLimit
+	stageAuth
+	stageLimits
+	stageCache
+	stageEncoding
+	stageBody
+)
+
+// Middleware de la cadena con su etapa
+type chainEntry struct {
+	stage int
+	name  string
+	wrap  mux.MiddlewareFunc
+}
+
+// Cadena de middlewares ordenada por etapas
+type middlewareChain struct {
+	entries []chainEntry
+}
+
+// Añadir un middleware; dentro de una misma etapa se respeta el orden de
+// llamada
+func (c *middlewareChain) use(stage int, name string, wrap mux.MiddlewareFunc) {
+	c.entries = append(c.entries, chainEntry{stage: stage, name: name, wrap: wrap})
+}
+
+// Middlewares en el orden en que se aplican, de fuera hacia dentro
+func (c *middlewareChain) ordered() []chainEntry {
+	entries := make([]chainEntry, len(c.entries))
+	copy(entries, c.entries)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].stage < entries[j].stage })
+	return entries
+}
+
+// Nombres de los middlewares en orden, para diagnósticos
+func (c *middlewareChain) names() []string {
+	var names []string
+	for _, entry := range c.ordered() {
+		names = append(names, entry.name)
+	}
+	return names
+}
+
+// Registrar la cadena en el router; como los de mux, solo se ejecutan en
+// las rutas que coinciden
+func (c *middlewareChain) apply(r *mux.Router) {
+	for _, entry := range c.ordered() {
+		r.Use(entry.wrap)
+	}
+}
+
+// Cadena por defecto según config
+func (srv *server) defaultChain() *middlewareChain {
+	c := &middlewareChain{}
+	c.use(stageRecovery, "recovery", srv.recoveryMiddleware)
+	c.use(stageCorrelation, "request_id", requestIDMiddleware)
+	c.use(stageCorrelation, "feature_flags", srv.featureFlagsMiddleware)
+	c.use(stageLogging, "logging", srv.loggingMiddleware)
+	c.use(stageHeaders, "https", srv.httpsMiddleware)
+	c.use(stageHeaders, "build_version", buildVersionMiddleware)
+	c.use(stageHeaders, "cors", srv.corsMiddleware)
+	c.use(stageHeaders, "route_headers", srv.routeHeadersMiddleware)
+	c.use(stageAuth, "auth", srv.authMiddleware)
+	c.use(stageAuth, "maintenance", srv.maintenanceMiddleware)
+	if srv.config.ReplayProtection {
+		c.use(stageAuth, "replay_protection", srv.replayProtectionMiddleware)
+	}
+	c.use(stageLimits, "timeout", srv.timeoutMiddleware)
+	if srv.chaosEnabled() {
+		c.use(stageLimits, "chaos", srv.chaosMiddleware)
+	}
+	if srv.config.RateLimitRPS > 0 {
+		limiter := newRateLimiter(srv.config.RateLimitRPS, srv.config.RateLimitBurst, srv.stop)
+		limiter.overrides = make(map[string]RateLimit, len(srv.config.RateLimitKeys))
+		for key, limit := range srv.config.RateLimitKeys {
+			limiter.overrides["key:"+key] = limit
+		}
+		if srv.config.EmailCheckRPS > 0 {
+			limiter.exempt = func(r *http.Request) bool { return r.URL.Path == emailAvailablePath }
+		}
+		c.use(stageRateLimit, "rate_limit", srv.rateLimitMiddleware(limiter))
+	}
+	c.use(stageLimits, "strict_query", srv.strictQueryMiddleware)
+	if srv.config.ResponseCacheTTL > 0 {
+		c.use(stageCache, "response_cache", srv.responseCacheMiddleware(newResponseCache(srv.config.ResponseCacheTTL, srv.config.ResponseCacheSize, &srv.dataVersion)))
+	}
+	c.use(stageEncoding, "compress", srv.compressMiddleware)
+	c.use(stageBody, "max_body", srv.maxBodyMiddleware)
+	if srv.config.MinBodyReadRate > 0 {
+		c.use(stageBody, "min_body_rate", srv.minBodyRateMiddleware)
+	}
+	c.use(stageBody, "request_decompress", srv.requestDecompressMiddleware)
+	c.use(stageBody, "utf8_body", srv.utf8BodyMiddleware)
+	if srv.config.DedupWindow > 0 {
+		c.use(stageBody, "dedup", srv.dedupMiddleware(newDedupCache(srv.config.DedupWindow)))
+	}
+	return c
+}
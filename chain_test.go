@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestRecoveryCatchesPanicsFromAuthStage(t *testing.T) {
+	srv := newServer(memoryStore{}, defaultConfig())
+	chain := srv.defaultChain()
+	// Se añade después de recovery pero en la etapa de auth, que va dentro
+	chain.use(stageAuth, "panicking_auth", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("auth exploded")
+		})
+	})
+	router := mux.NewRouter()
+	chain.apply(router)
+	router.HandleFunc("/api/users", srv.getUsersHandler).Methods("GET")
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	resp, data := doRequest(t, ts, "GET", "/api/users", "")
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("status %d, want 500: %s", resp.StatusCode, data)
+	}
+	if got := decodeResponse(t, data).Code; got != codeInternalError {
+		t.Errorf("code %q, want %q", got, codeInternalError)
+	}
+}
+
+func TestChainOrdersByStageNotByCallOrder(t *testing.T) {
+	pass := func(next http.Handler) http.Handler { return next }
+	c := &middlewareChain{}
+	c.use(stageAuth, "auth", pass)
+	c.use(stageLogging, "logging", pass)
+	c.use(stageRecovery, "recovery", pass)
+
+	want := []string{"recovery", "logging", "auth"}
+	if names := c.names(); !slices.Equal(names, want) {
+		t.Errorf("names %v, want %v", names, want)
+	}
+}
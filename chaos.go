@@ -0,0 +1,44 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Inyección de fallos para probar timeouts y reintentos de los clientes.
+// Solo para desarrollo: no hace nada si CHAOS_LATENCY_MS y CHAOS_ERROR_RATE
+// están a 0, y selfCheck avisa en el arranque cuando está activa.
+func (srv *server) chaosEnabled() bool {
+	return srv.config.ChaosLatencyMS > 0 || srv.config.ChaosErrorRate > 0
+}
+
+// Middleware que retrasa cada petición CHAOS_LATENCY_MS y hace fallar con
+// 500 una fracción CHAOS_ERROR_RATE de ellas. Los health checks quedan fuera
+// para que el orquestador no reinicie el servicio durante las pruebas.
+func (srv *server) chaosMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/health") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if srv.config.ChaosLatencyMS > 0 {
+			w.Header().Add("X-Chaos-Injected", "latency")
+			timer := time.NewTimer(time.Duration(srv.config.ChaosLatencyMS) * time.Millisecond)
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				timer.Stop()
+				return
+			}
+		}
+		if rand.Float64() < srv.config.ChaosErrorRate {
+			w.Header().Add("X-Chaos-Injected", "error")
+			srv.writeError(w, r, http.StatusInternalServerError, codeInternalError, "Injected failure (chaos testing)")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
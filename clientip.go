@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Obtener la IP real del cliente. X-Forwarded-For solo se tiene en cuenta
+// cuando la conexión viene de un proxy de confianza (TRUSTED_PROXIES); en
+// ese caso se recorre de derecha a izquierda saltando los proxies propios.
+func (srv *server) ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !srv.isTrustedProxy(host) {
+		return host
+	}
+
+	forwarded := r.Header.Values("X-Forwarded-For")
+	if len(forwarded) == 0 {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(forwarded, ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !srv.isTrustedProxy(hop) || i == 0 {
+			return hop
+		}
+	}
+	return host
+}
+
+// Comprobar si una IP pertenece a alguno de los rangos de confianza
+func (srv *server) isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range srv.config.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
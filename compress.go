@@ -0,0 +1,123 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Elegir el algoritmo de compresión según Accept-Encoding. Gana el de mayor
+// q entre los habilitados; a igualdad se prefiere br. "" significa identity.
+func negotiateEncoding(acceptEncoding string, enabled []string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		candidates := []string{name}
+		if name == "*" {
+			candidates = enabled
+		}
+		for _, c := range candidates {
+			if q <= 0 || !slices.Contains(enabled, c) {
+				continue
+			}
+			if q > bestQ || (q == bestQ && c == "br") {
+				best, bestQ = c, q
+			}
+		}
+	}
+	return best
+}
+
+// ResponseWriter que comprime el cuerpo con el algoritmo negociado
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	level       int
+	writer      io.WriteCloser
+	wroteHeader bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+
+	h := cw.Header()
+	if status != http.StatusNoContent && status != http.StatusNotModified && h.Get("Content-Encoding") == "" {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		if cw.encoding == "br" {
+			cw.writer = brotli.NewWriterLevel(cw.ResponseWriter, cw.level)
+		} else {
+			cw.writer, _ = gzip.NewWriterLevel(cw.ResponseWriter, cw.level)
+		}
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.writer == nil {
+		return cw.ResponseWriter.Write(b)
+	}
+	return cw.writer.Write(b)
+}
+
+// Vaciar lo comprimido hasta ahora para las respuestas en streaming. Un
+// Flush antes de escribir nada envía también las cabeceras, así que hay que
+// fijar Content-Encoding antes.
+func (cw *compressWriter) Flush() {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := cw.writer.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *compressWriter) close() {
+	if cw.writer != nil {
+		cw.writer.Close()
+	}
+}
+
+// Middleware de compresión de respuestas (br o gzip)
+func (srv *server) compressMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), srv.config.CompressionAlgorithms)
+		if encoding == "" || r.Method == "HEAD" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, level: srv.config.CompressionLevel}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Identificador de esta ejecución para las ETags de colección, ya que
+// dataVersion vuelve a empezar en cada arranque
+var bootID = strconv.FormatInt(time.Now().UnixNano(), 36)
+
+// ETag débil de una colección en la representación mediaType: depende de la
+// versión de los datos y del formato, no de los bytes serializados, así que
+// vale para cualquier compresión
+func collectionETag(version uint64, mediaType string) string {
+	return fmt.Sprintf(`W/"%s-%d-%s"`, bootID, version, representationTags[mediaType])
+}
+
+// ETag débil de un usuario en la representación mediaType, a partir de su
+// última modificación
+func userETag(user User, mediaType string) string {
+	return fmt.Sprintf(`W/"%d-%d-%s"`, user.ID, user.UpdatedAt.UnixNano(), representationTags[mediaType])
+}
+
+// Fijar ETag y Last-Modified y responder 304 si el cliente ya tiene la
+// versión actual. Con If-None-Match se ignora If-Modified-Since (RFC 9110
+// §13.1.3). Las fechas HTTP no tienen fracciones de segundo, así que esa
+// comparación se hace a resolución de segundos.
+func checkNotModified(w http.ResponseWriter, r *http.Request, etag string, modified time.Time) bool {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if !modified.IsZero() {
+		modified = modified.UTC().Truncate(time.Second)
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+	}
+
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		if etag == "" || !etagMatches(match, etag) {
+			return false
+		}
+	} else {
+		since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+		if modified.IsZero() || err != nil || modified.After(since) {
+			return false
+		}
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// Comparación débil de If-None-Match (RFC 9110 §8.8.3.2): W/ no cuenta y
+// basta con que coincida la etiqueta opaca de alguno de los valores
+func etagMatches(header, etag string) bool {
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
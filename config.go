@@ -0,0 +1,1103 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Configuración del servicio cargada al arrancar
+type Config struct {
+	Port                 string
+	DataFile             string
+	SlowRequestThreshold time.Duration
+	CORSAllowOrigin      string
+	ReadTimeout          time.Duration
+	WriteTimeout         time.Duration
+	IdleTimeout          time.Duration
+	DefaultEmailDomain   string
+	RateLimitRPS         float64
+	RateLimitBurst       int
+	// Límites propios de algunas API keys (clave -> límite), en lugar de
+	// RateLimitRPS y RateLimitBurst
+	RateLimitKeys map[string]RateLimit
+	// Límite propio de /api/users/email-available (0 usa el general)
+	EmailCheckRPS   float64
+	EmailCheckBurst int
+	// PersistMode "sync" escribe el archivo en cada cambio; "async" agrupa
+	// los cambios en como mucho una escritura por FlushInterval y escribe al
+	// apagar, por lo que una caída del proceso puede perder los cambios del
+	// último intervalo
+	PersistMode     string
+	FlushInterval   time.Duration
+	ShutdownTimeout time.Duration
+	BatchMaxIDs     int
+	TrustedProxies  []netip.Prefix
+	// API key -> ID del usuario al que pertenece; vacío desactiva la autenticación
+	APIKeys map[string]int
+	// Algoritmos de compresión habilitados ("br", "gzip") y nivel (1-9)
+	CompressionAlgorithms []string
+	CompressionLevel      int
+	// Caché de respuestas GET; un TTL de 0 la desactiva
+	ResponseCacheTTL  time.Duration
+	ResponseCacheSize int
+	// Ventana en la que un POST /api/users con el mismo cuerpo y cliente
+	// recibe la respuesta del primero en lugar de crear otro usuario (0 la
+	// desactiva)
+	DedupWindow time.Duration
+	// Ritmo mínimo de subida del cuerpo en bytes/s (0 lo desactiva) y
+	// margen inicial antes de exigirlo
+	MinBodyReadRate int
+	BodyReadGrace   time.Duration
+	// Certificado y clave para servir HTTPS; vacíos sirven HTTP plano
+	TLSCertFile string
+	TLSKeyFile  string
+	// Versión mínima de TLS y cipher suites permitidas (vacío usa las de Go)
+	TLSMinVersion   uint16
+	TLSCipherSuites []uint16
+	// Redirigir a HTTPS lo que llegue en HTTP plano (ver https.go):
+	// HTTPSRedirect lo activa sin TLS propio, con TLS terminado en un proxy, y
+	// HTTPRedirectPort abre un puerto plano que solo redirige. HSTS queda
+	// desactivado con HSTSMaxAge a 0.
+	HTTPSRedirect         bool
+	HTTPRedirectPort      string
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	// Fallos consecutivos de escritura que abren el circuit breaker del
+	// store (0 lo desactiva) y tiempo que permanece abierto
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// Intentos totales ante errores transitorios del store (1 no reintenta)
+	// y espera base del backoff exponencial. Se aplican a la carga, al flush
+	// async y a las escrituras síncronas; estas, hechas con usersMu
+	// bloqueado, esperan en total como mucho lockedRetryBudget.
+	StoreRetryAttempts  int
+	StoreRetryBaseDelay time.Duration
+	// Detener el arranque si los datos cargados tienen emails duplicados
+	FailOnDuplicateEmails bool
+	// Incluir el mensaje y la traza de los panics en la respuesta 500; solo
+	// para desarrollo
+	DebugErrors bool
+	// Plazo de cada comprobación de /health/ready
+	HealthCheckTimeout time.Duration
+	// Tamaño máximo del cuerpo de las peticiones en bytes
+	MaxBodyBytes int64
+	// Límite propio de POST /api/users/bulk, que se decodifica en streaming
+	BulkMaxBodyBytes int64
+	// Retry-After por defecto de las respuestas 429 y 503 sin un plazo propio
+	RetryAfter time.Duration
+	// Anidamiento máximo de objetos y arrays en los cuerpos JSON
+	MaxJSONDepth int
+	// Nivel de log: "info" o "debug"
+	LogLevel string
+	// Redacción de los datos sensibles en el log (emails, API keys y los
+	// campos de sensitiveLogFields): "off", "mask" o "hash"
+	LogRedact string
+	// Formato de los errores de validación: "structured" o "flat"
+	ValidationErrors string
+	// Rol de los usuarios nuevos que no indican ninguno (vacío lo deja sin rol)
+	DefaultRole string
+	// Entradas que se guardan por cliente de /admin/logs/stream antes de
+	// descartar las más antiguas
+	LogStreamBuffer int
+	// Solo para desarrollo: latencia añadida a cada petición y fracción de
+	// peticiones que fallan a propósito (ver chaos.go)
+	ChaosLatencyMS int
+	ChaosErrorRate float64
+	// Arrancar en modo mantenimiento (ver maintenance.go)
+	MaintenanceMode bool
+	// Responder 400 a los parámetros de query que el endpoint no reconoce
+	// en lugar de ignorarlos (ver knownQueryParams)
+	StrictQueryParams bool
+	// Exigir X-Request-Nonce y X-Request-Timestamp en las peticiones que
+	// modifican datos; los nonces se recuerdan NonceTTL y la marca de
+	// tiempo puede desviarse como mucho MaxClockSkew
+	ReplayProtection bool
+	NonceTTL         time.Duration
+	MaxClockSkew     time.Duration
+	// Parámetros de filtro admitidos en el listado y la exportación
+	MaxQueryParams int
+	// Dominios de email admitidos al crear o modificar usuarios (vacío
+	// admite cualquiera)
+	AllowedEmailDomains []string
+	// Campos de usuario que no pueden repetirse entre usuarios no eliminados
+	// (ver uniqueUserFields); email siempre debe estar
+	UniqueFields []string
+	// Entradas del historial de cambios que se conservan por usuario (0 lo
+	// desactiva)
+	HistoryLimit int
+	// Tamaño máximo en bytes de los buffers de respuesta JSON que se
+	// reutilizan (0 desactiva la reutilización)
+	JSONPoolMaxBuffer int
+	// Flags de funcionalidad activas por defecto (ver featureflags.go); cada
+	// petición puede cambiarlas con X-Feature-Flags
+	FeatureFlags []string
+	// Cada cuánto se comprueba si el archivo de datos cambió fuera del
+	// servicio para recargarlo (0 lo desactiva) y cómo se recarga
+	WatchInterval time.Duration
+	ReloadPolicy  string
+	// Cabeceras fijas por ruta; la clave es "MÉTODO /plantilla" o solo la
+	// plantilla de mux para todos los métodos, p. ej. "GET /api/users"
+	RouteHeaders map[string]map[string]string
+	// Tiempo máximo por petición (0 lo desactiva) y excepciones por ruta con
+	// las mismas claves que RouteHeaders. Las de ROUTE_TIMEOUTS se añaden a
+	// las de por defecto. El plazo también mueve el límite de escritura de
+	// la conexión, así que una ruta puede superar WRITE_TIMEOUT; el de
+	// lectura del cuerpo (READ_TIMEOUT) no cambia.
+	RequestTimeout time.Duration
+	RouteTimeouts  map[string]time.Duration
+	// Pasos de normalización por campo (trim, collapse_spaces, lowercase)
+	// que se aplican antes de validar. Los de FIELD_NORMALIZATION sustituyen
+	// a los de por defecto campo a campo; una lista vacía lo desactiva.
+	FieldNormalization map[string][]string
+}
+
+// Valores por defecto de la configuración
+func defaultConfig() Config {
+	return Config{
+		Port:                  "8080",
+		SlowRequestThreshold:  time.Second,
+		CORSAllowOrigin:       "*",
+		ReadTimeout:           10 * time.Second,
+		WriteTimeout:          30 * time.Second,
+		IdleTimeout:           120 * time.Second,
+		RateLimitBurst:        20,
+		EmailCheckBurst:       10,
+		PersistMode:           "sync",
+		FlushInterval:         5 * time.Second,
+		ShutdownTimeout:       10 * time.Second,
+		BatchMaxIDs:           100,
+		CompressionAlgorithms: []string{"br", "gzip"},
+		CompressionLevel:      6,
+		ResponseCacheSize:     256,
+		BodyReadGrace:         2 * time.Second,
+		TLSMinVersion:         tls.VersionTLS12,
+		BreakerThreshold:      5,
+		BreakerCooldown:       30 * time.Second,
+		StoreRetryAttempts:    3,
+		StoreRetryBaseDelay:   50 * time.Millisecond,
+		LogLevel:              "info",
+		LogRedact:             redactHash,
+		ValidationErrors:      validationStructured,
+		ReloadPolicy:          reloadReplace,
+		DefaultRole:           roleMember,
+		LogStreamBuffer:       256,
+		MaxQueryParams:        10,
+		NonceTTL:              10 * time.Minute,
+		MaxClockSkew:          5 * time.Minute,
+		RequestTimeout:        30 * time.Second,
+		RouteTimeouts: map[string]time.Duration{
+			"GET /api/users/export":  5 * time.Minute,
+			"GET /admin/logs/stream": 0,
+		},
+		FieldNormalization: map[string][]string{
+			"name":  {"trim"},
+			"email": {"trim", "lowercase"},
+		},
+		UniqueFields:       []string{"email"},
+		HistoryLimit:       100,
+		JSONPoolMaxBuffer:  64 << 10,
+		MaxJSONDepth:       32,
+		RetryAfter:         30 * time.Second,
+		HealthCheckTimeout: 2 * time.Second,
+		MaxBodyBytes:       1 << 20,
+		BulkMaxBodyBytes:   64 << 20,
+	}
+}
+
+// Formato del archivo de configuración (CONFIG_FILE); las duraciones se
+// escriben como "500ms", "2s", etc.
+type configFile struct {
+	Port                  *string                      `json:"port"`
+	DataFile              *string                      `json:"data_file"`
+	SlowRequestThreshold  *string                      `json:"slow_request_threshold"`
+	CORSAllowOrigin       *string                      `json:"cors_allow_origin"`
+	ReadTimeout           *string                      `json:"read_timeout"`
+	WriteTimeout          *string                      `json:"write_timeout"`
+	IdleTimeout           *string                      `json:"idle_timeout"`
+	DefaultEmailDomain    *string                      `json:"default_email_domain"`
+	RateLimitRPS          *float64                     `json:"rate_limit_rps"`
+	EmailCheckRPS         *float64                     `json:"email_check_rps"`
+	EmailCheckBurst       *int                         `json:"email_check_burst"`
+	RateLimitBurst        *int                         `json:"rate_limit_burst"`
+	PersistMode           *string                      `json:"persist_mode"`
+	FlushInterval         *string                      `json:"flush_interval"`
+	ShutdownTimeout       *string                      `json:"shutdown_timeout"`
+	BatchMaxIDs           *int                         `json:"batch_max_ids"`
+	TrustedProxies        []string                     `json:"trusted_proxies"`
+	APIKeys               map[string]int               `json:"api_keys"`
+	RateLimitKeys         map[string]RateLimit         `json:"rate_limit_keys"`
+	Compression           []string                     `json:"compression"`
+	CompressionLevel      *int                         `json:"compression_level"`
+	ResponseCacheTTL      *string                      `json:"response_cache_ttl"`
+	ResponseCacheSize     *int                         `json:"response_cache_size"`
+	DedupWindow           *string                      `json:"dedup_window"`
+	MinBodyReadRate       *int                         `json:"min_body_read_rate"`
+	BodyReadGrace         *string                      `json:"body_read_grace"`
+	TLSCertFile           *string                      `json:"tls_cert_file"`
+	TLSKeyFile            *string                      `json:"tls_key_file"`
+	TLSMinVersion         *string                      `json:"tls_min_version"`
+	TLSCipherSuites       []string                     `json:"tls_cipher_suites"`
+	HTTPSRedirect         *bool                        `json:"https_redirect"`
+	HTTPRedirectPort      *string                      `json:"http_redirect_port"`
+	HSTSMaxAge            *string                      `json:"hsts_max_age"`
+	HSTSIncludeSubdomains *bool                        `json:"hsts_include_subdomains"`
+	DebugErrors           *bool                        `json:"debug_errors"`
+	FailOnDuplicateEmails *bool                        `json:"fail_on_duplicate_emails"`
+	LogLevel              *string                      `json:"log_level"`
+	LogRedact             *string                      `json:"log_redact"`
+	ValidationErrors      *string                      `json:"validation_errors"`
+	MaxQueryParams        *int                         `json:"max_query_params"`
+	HistoryLimit          *int                         `json:"history_limit"`
+	JSONPoolMaxBuffer     *int                         `json:"json_pool_max_buffer"`
+	FeatureFlags          []string                     `json:"feature_flags"`
+	ChaosLatencyMS        *int                         `json:"chaos_latency_ms"`
+	ChaosErrorRate        *float64                     `json:"chaos_error_rate"`
+	MaintenanceMode       *bool                        `json:"maintenance_mode"`
+	StrictQueryParams     *bool                        `json:"strict_query_params"`
+	ReplayProtection      *bool                        `json:"replay_protection"`
+	NonceTTL              *string                      `json:"nonce_ttl"`
+	MaxClockSkew          *string                      `json:"max_clock_skew"`
+	LogStreamBuffer       *int                         `json:"log_stream_buffer"`
+	DefaultRole           *string                      `json:"default_role"`
+	AllowedEmailDomains   []string                     `json:"allowed_email_domains"`
+	UniqueFields          []string                     `json:"unique_fields"`
+	WatchInterval         *string                      `json:"watch_interval"`
+	ReloadPolicy          *string                      `json:"reload_policy"`
+	MaxJSONDepth          *int                         `json:"max_json_depth"`
+	RetryAfter            *string                      `json:"retry_after"`
+	HealthCheckTimeout    *string                      `json:"health_check_timeout"`
+	MaxBodyBytes          *int64                       `json:"max_body_bytes"`
+	BulkMaxBodyBytes      *int64                       `json:"bulk_max_body_bytes"`
+	RouteHeaders          map[string]map[string]string `json:"route_headers"`
+	RequestTimeout        *string                      `json:"request_timeout"`
+	RouteTimeouts         map[string]string            `json:"route_timeouts"`
+	FieldNormalization    map[string][]string          `json:"field_normalization"`
+	BreakerThreshold      *int                         `json:"breaker_threshold"`
+	BreakerCooldown       *string                      `json:"breaker_cooldown"`
+	StoreRetryAttempts    *int                         `json:"store_retry_attempts"`
+	StoreRetryBaseDelay   *string                      `json:"store_retry_base_delay"`
+}
+
+// Cargar la configuración: valores por defecto, luego el archivo
+// CONFIG_FILE (si existe) y por último las variables de entorno. Es el único
+// sitio que lee el entorno; el error reúne todos los problemas encontrados,
+// incluido un archivo ilegible, para corregirlos de una vez.
+func LoadConfig() (Config, error) {
+	cfg := defaultConfig()
+	var problems []error
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := applyConfigFile(&cfg, path, &problems); err != nil {
+			problems = append(problems, fmt.Errorf("config file %s: %w", path, err))
+		}
+	}
+
+	setString(&cfg.Port, os.Getenv("PORT"))
+	setString(&cfg.DataFile, os.Getenv("DATA_FILE"))
+	setString(&cfg.CORSAllowOrigin, os.Getenv("CORS_ALLOW_ORIGIN"))
+	setString(&cfg.DefaultEmailDomain, os.Getenv("DEFAULT_EMAIL_DOMAIN"))
+	setString(&cfg.PersistMode, os.Getenv("PERSIST_MODE"))
+	setString(&cfg.LogLevel, os.Getenv("LOG_LEVEL"))
+	setString(&cfg.LogRedact, os.Getenv("LOG_REDACT"))
+	setString(&cfg.ValidationErrors, os.Getenv("VALIDATION_ERRORS"))
+	setInt(&cfg.LogStreamBuffer, "LOG_STREAM_BUFFER", os.Getenv("LOG_STREAM_BUFFER"), &problems)
+	setInt(&cfg.MaxQueryParams, "MAX_QUERY_PARAMS", os.Getenv("MAX_QUERY_PARAMS"), &problems)
+	setInt(&cfg.HistoryLimit, "HISTORY_LIMIT", os.Getenv("HISTORY_LIMIT"), &problems)
+	setInt(&cfg.JSONPoolMaxBuffer, "JSON_POOL_MAX_BUFFER", os.Getenv("JSON_POOL_MAX_BUFFER"), &problems)
+	setInt(&cfg.ChaosLatencyMS, "CHAOS_LATENCY_MS", os.Getenv("CHAOS_LATENCY_MS"), &problems)
+	setFloat(&cfg.ChaosErrorRate, "CHAOS_ERROR_RATE", os.Getenv("CHAOS_ERROR_RATE"), &problems)
+	setBool(&cfg.MaintenanceMode, "MAINTENANCE_MODE", os.Getenv("MAINTENANCE_MODE"), &problems)
+	setBool(&cfg.StrictQueryParams, "STRICT_QUERY_PARAMS", os.Getenv("STRICT_QUERY_PARAMS"), &problems)
+	setBool(&cfg.ReplayProtection, "REPLAY_PROTECTION", os.Getenv("REPLAY_PROTECTION"), &problems)
+	setDuration(&cfg.NonceTTL, "NONCE_TTL", os.Getenv("NONCE_TTL"), &problems)
+	setDuration(&cfg.MaxClockSkew, "MAX_CLOCK_SKEW", os.Getenv("MAX_CLOCK_SKEW"), &problems)
+	if v, ok := os.LookupEnv("DEFAULT_ROLE"); ok {
+		cfg.DefaultRole = v
+	}
+	if v := os.Getenv("ALLOWED_EMAIL_DOMAINS"); v != "" {
+		cfg.AllowedEmailDomains = parseDomains(strings.Split(v, ","))
+	}
+	if v := os.Getenv("FEATURE_FLAGS"); v != "" {
+		cfg.FeatureFlags = parseFieldNames(strings.Split(v, ","))
+	}
+	if v := os.Getenv("UNIQUE_FIELDS"); v != "" {
+		cfg.UniqueFields = parseFieldNames(strings.Split(v, ","))
+	}
+	setDuration(&cfg.WatchInterval, "WATCH_INTERVAL", os.Getenv("WATCH_INTERVAL"), &problems)
+	setString(&cfg.ReloadPolicy, os.Getenv("RELOAD_POLICY"))
+	setString(&cfg.TLSCertFile, os.Getenv("TLS_CERT_FILE"))
+	setString(&cfg.TLSKeyFile, os.Getenv("TLS_KEY_FILE"))
+	setTLSVersion(&cfg.TLSMinVersion, "TLS_MIN_VERSION", os.Getenv("TLS_MIN_VERSION"), &problems)
+	if v := os.Getenv("TLS_CIPHER_SUITES"); v != "" {
+		setCipherSuites(&cfg.TLSCipherSuites, "TLS_CIPHER_SUITES", strings.Split(v, ","), &problems)
+	}
+	setBool(&cfg.HTTPSRedirect, "HTTPS_REDIRECT", os.Getenv("HTTPS_REDIRECT"), &problems)
+	setString(&cfg.HTTPRedirectPort, os.Getenv("HTTP_REDIRECT_PORT"))
+	setDuration(&cfg.HSTSMaxAge, "HSTS_MAX_AGE", os.Getenv("HSTS_MAX_AGE"), &problems)
+	setBool(&cfg.HSTSIncludeSubdomains, "HSTS_INCLUDE_SUBDOMAINS", os.Getenv("HSTS_INCLUDE_SUBDOMAINS"), &problems)
+	if v := os.Getenv("TRUSTED_PROXIES"); v != "" {
+		setPrefixes(&cfg.TrustedProxies, "TRUSTED_PROXIES", strings.Split(v, ","), &problems)
+	}
+	if v := os.Getenv("API_KEYS"); v != "" {
+		setAPIKeys(&cfg.APIKeys, v, &problems)
+	}
+	if v := os.Getenv("RATE_LIMIT_KEYS"); v != "" {
+		setRateLimitKeys(&cfg.RateLimitKeys, v, &problems)
+	}
+	if v := os.Getenv("COMPRESSION"); v != "" {
+		cfg.CompressionAlgorithms = parseCompression(strings.Split(v, ","))
+	}
+	setInt(&cfg.CompressionLevel, "COMPRESSION_LEVEL", os.Getenv("COMPRESSION_LEVEL"), &problems)
+	setDuration(&cfg.ResponseCacheTTL, "RESPONSE_CACHE_TTL", os.Getenv("RESPONSE_CACHE_TTL"), &problems)
+	setInt(&cfg.ResponseCacheSize, "RESPONSE_CACHE_SIZE", os.Getenv("RESPONSE_CACHE_SIZE"), &problems)
+	setDuration(&cfg.DedupWindow, "DEDUP_WINDOW", os.Getenv("DEDUP_WINDOW"), &problems)
+	setInt(&cfg.MinBodyReadRate, "MIN_BODY_READ_RATE", os.Getenv("MIN_BODY_READ_RATE"), &problems)
+	setDuration(&cfg.BodyReadGrace, "BODY_READ_GRACE", os.Getenv("BODY_READ_GRACE"), &problems)
+	setInt(&cfg.BreakerThreshold, "BREAKER_THRESHOLD", os.Getenv("BREAKER_THRESHOLD"), &problems)
+	setDuration(&cfg.BreakerCooldown, "BREAKER_COOLDOWN", os.Getenv("BREAKER_COOLDOWN"), &problems)
+	if v := os.Getenv("ROUTE_HEADERS"); v != "" {
+		if err := json.Unmarshal([]byte(v), &cfg.RouteHeaders); err != nil {
+			problems = append(problems, fmt.Errorf("ROUTE_HEADERS must be a JSON object of route -> headers: %v", err))
+		}
+	}
+	setDuration(&cfg.RequestTimeout, "REQUEST_TIMEOUT", os.Getenv("REQUEST_TIMEOUT"), &problems)
+	if v := os.Getenv("ROUTE_TIMEOUTS"); v != "" {
+		var timeouts map[string]string
+		if err := json.Unmarshal([]byte(v), &timeouts); err != nil {
+			problems = append(problems, fmt.Errorf("ROUTE_TIMEOUTS must be a JSON object of route -> duration: %v", err))
+		} else {
+			setRouteTimeouts(cfg.RouteTimeouts, "ROUTE_TIMEOUTS", timeouts, &problems)
+		}
+	}
+	if v := os.Getenv("FIELD_NORMALIZATION"); v != "" {
+		var steps map[string][]string
+		if err := json.Unmarshal([]byte(v), &steps); err != nil {
+			problems = append(problems, fmt.Errorf("FIELD_NORMALIZATION must be a JSON object of field -> list of steps: %v", err))
+		} else {
+			setFieldNormalization(cfg.FieldNormalization, steps)
+		}
+	}
+	setInt(&cfg.StoreRetryAttempts, "STORE_RETRY_ATTEMPTS", os.Getenv("STORE_RETRY_ATTEMPTS"), &problems)
+	setDuration(&cfg.StoreRetryBaseDelay, "STORE_RETRY_BASE_DELAY", os.Getenv("STORE_RETRY_BASE_DELAY"), &problems)
+	setDuration(&cfg.HealthCheckTimeout, "HEALTH_CHECK_TIMEOUT", os.Getenv("HEALTH_CHECK_TIMEOUT"), &problems)
+	setInt64(&cfg.MaxBodyBytes, "MAX_BODY_BYTES", os.Getenv("MAX_BODY_BYTES"), &problems)
+	setInt64(&cfg.BulkMaxBodyBytes, "BULK_MAX_BODY_BYTES", os.Getenv("BULK_MAX_BODY_BYTES"), &problems)
+	setDuration(&cfg.RetryAfter, "RETRY_AFTER", os.Getenv("RETRY_AFTER"), &problems)
+	setInt(&cfg.MaxJSONDepth, "MAX_JSON_DEPTH", os.Getenv("MAX_JSON_DEPTH"), &problems)
+	setBool(&cfg.FailOnDuplicateEmails, "FAIL_ON_DUPLICATE_EMAILS", os.Getenv("FAIL_ON_DUPLICATE_EMAILS"), &problems)
+	setBool(&cfg.DebugErrors, "DEBUG_ERRORS", os.Getenv("DEBUG_ERRORS"), &problems)
+	setInt(&cfg.BatchMaxIDs, "BATCH_MAX_IDS", os.Getenv("BATCH_MAX_IDS"), &problems)
+	setFloat(&cfg.RateLimitRPS, "RATE_LIMIT_RPS", os.Getenv("RATE_LIMIT_RPS"), &problems)
+	setInt(&cfg.RateLimitBurst, "RATE_LIMIT_BURST", os.Getenv("RATE_LIMIT_BURST"), &problems)
+	setFloat(&cfg.EmailCheckRPS, "EMAIL_CHECK_RPS", os.Getenv("EMAIL_CHECK_RPS"), &problems)
+	setInt(&cfg.EmailCheckBurst, "EMAIL_CHECK_BURST", os.Getenv("EMAIL_CHECK_BURST"), &problems)
+	setDuration(&cfg.SlowRequestThreshold, "SLOW_REQUEST_THRESHOLD", os.Getenv("SLOW_REQUEST_THRESHOLD"), &problems)
+	setDuration(&cfg.ReadTimeout, "READ_TIMEOUT", os.Getenv("READ_TIMEOUT"), &problems)
+	setDuration(&cfg.WriteTimeout, "WRITE_TIMEOUT", os.Getenv("WRITE_TIMEOUT"), &problems)
+	setDuration(&cfg.IdleTimeout, "IDLE_TIMEOUT", os.Getenv("IDLE_TIMEOUT"), &problems)
+	setDuration(&cfg.FlushInterval, "FLUSH_INTERVAL", os.Getenv("FLUSH_INTERVAL"), &problems)
+	setDuration(&cfg.ShutdownTimeout, "SHUTDOWN_TIMEOUT", os.Getenv("SHUTDOWN_TIMEOUT"), &problems)
+
+	problems = append(problems, cfg.validate()...)
+	return cfg, errors.Join(problems...)
+}
+
+// Aplicar los valores presentes en el archivo de configuración. Una clave
+// desconocida (una errata, normalmente) es un problema más; el resto del
+// archivo se aplica igual para informar de todos a la vez.
+func applyConfigFile(cfg *Config, path string, problems *[]error) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var file configFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		if !strings.HasPrefix(err.Error(), "json: unknown field") {
+			return err
+		}
+		*problems = append(*problems, fmt.Errorf("config file %s: %w", path, err))
+		file = configFile{}
+		if err := json.Unmarshal(data, &file); err != nil {
+			return err
+		}
+	} else if dec.More() {
+		return errors.New("unexpected data after the configuration object")
+	}
+
+	if file.Port != nil {
+		cfg.Port = *file.Port
+	}
+	if file.DataFile != nil {
+		cfg.DataFile = *file.DataFile
+	}
+	if file.CORSAllowOrigin != nil {
+		cfg.CORSAllowOrigin = *file.CORSAllowOrigin
+	}
+	if file.DefaultEmailDomain != nil {
+		cfg.DefaultEmailDomain = *file.DefaultEmailDomain
+	}
+	if file.RateLimitRPS != nil {
+		cfg.RateLimitRPS = *file.RateLimitRPS
+	}
+	if file.RateLimitBurst != nil {
+		cfg.RateLimitBurst = *file.RateLimitBurst
+	}
+	if file.EmailCheckRPS != nil {
+		cfg.EmailCheckRPS = *file.EmailCheckRPS
+	}
+	if file.EmailCheckBurst != nil {
+		cfg.EmailCheckBurst = *file.EmailCheckBurst
+	}
+	if file.SlowRequestThreshold != nil {
+		setDuration(&cfg.SlowRequestThreshold, "slow_request_threshold", *file.SlowRequestThreshold, problems)
+	}
+	if file.ReadTimeout != nil {
+		setDuration(&cfg.ReadTimeout, "read_timeout", *file.ReadTimeout, problems)
+	}
+	if file.WriteTimeout != nil {
+		setDuration(&cfg.WriteTimeout, "write_timeout", *file.WriteTimeout, problems)
+	}
+	if file.IdleTimeout != nil {
+		setDuration(&cfg.IdleTimeout, "idle_timeout", *file.IdleTimeout, problems)
+	}
+	if file.TrustedProxies != nil {
+		setPrefixes(&cfg.TrustedProxies, "trusted_proxies", file.TrustedProxies, problems)
+	}
+	if file.APIKeys != nil {
+		cfg.APIKeys = file.APIKeys
+	}
+	if file.RateLimitKeys != nil {
+		cfg.RateLimitKeys = file.RateLimitKeys
+	}
+	if file.Compression != nil {
+		cfg.CompressionAlgorithms = parseCompression(file.Compression)
+	}
+	if file.CompressionLevel != nil {
+		cfg.CompressionLevel = *file.CompressionLevel
+	}
+	if file.ResponseCacheTTL != nil {
+		setDuration(&cfg.ResponseCacheTTL, "response_cache_ttl", *file.ResponseCacheTTL, problems)
+	}
+	if file.ResponseCacheSize != nil {
+		cfg.ResponseCacheSize = *file.ResponseCacheSize
+	}
+	if file.DedupWindow != nil {
+		setDuration(&cfg.DedupWindow, "dedup_window", *file.DedupWindow, problems)
+	}
+	if file.MinBodyReadRate != nil {
+		cfg.MinBodyReadRate = *file.MinBodyReadRate
+	}
+	if file.BodyReadGrace != nil {
+		setDuration(&cfg.BodyReadGrace, "body_read_grace", *file.BodyReadGrace, problems)
+	}
+	if file.TLSCertFile != nil {
+		cfg.TLSCertFile = *file.TLSCertFile
+	}
+	if file.TLSKeyFile != nil {
+		cfg.TLSKeyFile = *file.TLSKeyFile
+	}
+	if file.TLSMinVersion != nil {
+		setTLSVersion(&cfg.TLSMinVersion, "tls_min_version", *file.TLSMinVersion, problems)
+	}
+	if file.TLSCipherSuites != nil {
+		setCipherSuites(&cfg.TLSCipherSuites, "tls_cipher_suites", file.TLSCipherSuites, problems)
+	}
+	if file.HTTPSRedirect != nil {
+		cfg.HTTPSRedirect = *file.HTTPSRedirect
+	}
+	if file.HTTPRedirectPort != nil {
+		cfg.HTTPRedirectPort = *file.HTTPRedirectPort
+	}
+	if file.HSTSMaxAge != nil {
+		setDuration(&cfg.HSTSMaxAge, "hsts_max_age", *file.HSTSMaxAge, problems)
+	}
+	if file.HSTSIncludeSubdomains != nil {
+		cfg.HSTSIncludeSubdomains = *file.HSTSIncludeSubdomains
+	}
+	if file.BreakerThreshold != nil {
+		cfg.BreakerThreshold = *file.BreakerThreshold
+	}
+	if file.BreakerCooldown != nil {
+		setDuration(&cfg.BreakerCooldown, "breaker_cooldown", *file.BreakerCooldown, problems)
+	}
+	if file.RouteHeaders != nil {
+		cfg.RouteHeaders = file.RouteHeaders
+	}
+	if file.RequestTimeout != nil {
+		setDuration(&cfg.RequestTimeout, "request_timeout", *file.RequestTimeout, problems)
+	}
+	if file.RouteTimeouts != nil {
+		setRouteTimeouts(cfg.RouteTimeouts, "route_timeouts", file.RouteTimeouts, problems)
+	}
+	if file.FieldNormalization != nil {
+		setFieldNormalization(cfg.FieldNormalization, file.FieldNormalization)
+	}
+	if file.HealthCheckTimeout != nil {
+		setDuration(&cfg.HealthCheckTimeout, "health_check_timeout", *file.HealthCheckTimeout, problems)
+	}
+	if file.MaxBodyBytes != nil {
+		cfg.MaxBodyBytes = *file.MaxBodyBytes
+	}
+	if file.BulkMaxBodyBytes != nil {
+		cfg.BulkMaxBodyBytes = *file.BulkMaxBodyBytes
+	}
+	if file.RetryAfter != nil {
+		setDuration(&cfg.RetryAfter, "retry_after", *file.RetryAfter, problems)
+	}
+	if file.MaxJSONDepth != nil {
+		cfg.MaxJSONDepth = *file.MaxJSONDepth
+	}
+	if file.LogLevel != nil {
+		cfg.LogLevel = *file.LogLevel
+	}
+	if file.LogRedact != nil {
+		cfg.LogRedact = *file.LogRedact
+	}
+	if file.ValidationErrors != nil {
+		cfg.ValidationErrors = *file.ValidationErrors
+	}
+	if file.LogStreamBuffer != nil {
+		cfg.LogStreamBuffer = *file.LogStreamBuffer
+	}
+	if file.MaxQueryParams != nil {
+		cfg.MaxQueryParams = *file.MaxQueryParams
+	}
+	if file.HistoryLimit != nil {
+		cfg.HistoryLimit = *file.HistoryLimit
+	}
+	if file.JSONPoolMaxBuffer != nil {
+		cfg.JSONPoolMaxBuffer = *file.JSONPoolMaxBuffer
+	}
+	if file.ChaosLatencyMS != nil {
+		cfg.ChaosLatencyMS = *file.ChaosLatencyMS
+	}
+	if file.ChaosErrorRate != nil {
+		cfg.ChaosErrorRate = *file.ChaosErrorRate
+	}
+	if file.MaintenanceMode != nil {
+		cfg.MaintenanceMode = *file.MaintenanceMode
+	}
+	if file.StrictQueryParams != nil {
+		cfg.StrictQueryParams = *file.StrictQueryParams
+	}
+	if file.ReplayProtection != nil {
+		cfg.ReplayProtection = *file.ReplayProtection
+	}
+	if file.NonceTTL != nil {
+		setDuration(&cfg.NonceTTL, "nonce_ttl", *file.NonceTTL, problems)
+	}
+	if file.MaxClockSkew != nil {
+		setDuration(&cfg.MaxClockSkew, "max_clock_skew", *file.MaxClockSkew, problems)
+	}
+	if file.DefaultRole != nil {
+		cfg.DefaultRole = *file.DefaultRole
+	}
+	if file.AllowedEmailDomains != nil {
+		cfg.AllowedEmailDomains = parseDomains(file.AllowedEmailDomains)
+	}
+	if file.FeatureFlags != nil {
+		cfg.FeatureFlags = parseFieldNames(file.FeatureFlags)
+	}
+	if file.UniqueFields != nil {
+		cfg.UniqueFields = parseFieldNames(file.UniqueFields)
+	}
+	if file.WatchInterval != nil {
+		setDuration(&cfg.WatchInterval, "watch_interval", *file.WatchInterval, problems)
+	}
+	if file.ReloadPolicy != nil {
+		cfg.ReloadPolicy = *file.ReloadPolicy
+	}
+	if file.StoreRetryAttempts != nil {
+		cfg.StoreRetryAttempts = *file.StoreRetryAttempts
+	}
+	if file.StoreRetryBaseDelay != nil {
+		setDuration(&cfg.StoreRetryBaseDelay, "store_retry_base_delay", *file.StoreRetryBaseDelay, problems)
+	}
+	if file.FailOnDuplicateEmails != nil {
+		cfg.FailOnDuplicateEmails = *file.FailOnDuplicateEmails
+	}
+	if file.DebugErrors != nil {
+		cfg.DebugErrors = *file.DebugErrors
+	}
+	if file.BatchMaxIDs != nil {
+		cfg.BatchMaxIDs = *file.BatchMaxIDs
+	}
+	if file.PersistMode != nil {
+		cfg.PersistMode = *file.PersistMode
+	}
+	if file.FlushInterval != nil {
+		setDuration(&cfg.FlushInterval, "flush_interval", *file.FlushInterval, problems)
+	}
+	if file.ShutdownTimeout != nil {
+		setDuration(&cfg.ShutdownTimeout, "shutdown_timeout", *file.ShutdownTimeout, problems)
+	}
+	return nil
+}
+
+// Comprobar que los valores de la configuración son coherentes
+func (c Config) validate() []error {
+	var problems []error
+
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		problems = append(problems, fmt.Errorf("port %q must be a number between 1 and 65535", c.Port))
+	}
+	if c.SlowRequestThreshold <= 0 {
+		problems = append(problems, errors.New("slow request threshold must be positive"))
+	}
+	if c.ReadTimeout <= 0 {
+		problems = append(problems, errors.New("read timeout must be positive"))
+	}
+	if c.WriteTimeout <= 0 {
+		problems = append(problems, errors.New("write timeout must be positive"))
+	}
+	if c.IdleTimeout <= 0 {
+		problems = append(problems, errors.New("idle timeout must be positive"))
+	}
+	if c.CORSAllowOrigin == "" {
+		problems = append(problems, errors.New("CORS allow origin must not be empty"))
+	}
+	if c.RateLimitRPS < 0 {
+		problems = append(problems, errors.New("rate limit RPS must not be negative (0 disables rate limiting)"))
+	}
+	if c.RateLimitRPS > 0 && c.RateLimitBurst < 1 {
+		problems = append(problems, errors.New("rate limit burst must be at least 1"))
+	}
+	if c.EmailCheckRPS < 0 {
+		problems = append(problems, errors.New("email check RPS must not be negative (0 uses the general rate limit)"))
+	}
+	if c.EmailCheckRPS > 0 && c.EmailCheckBurst < 1 {
+		problems = append(problems, errors.New("email check burst must be at least 1"))
+	}
+	if c.PersistMode != "sync" && c.PersistMode != "async" {
+		problems = append(problems, fmt.Errorf("persist mode %q must be \"sync\" or \"async\"", c.PersistMode))
+	}
+	if c.FlushInterval <= 0 {
+		problems = append(problems, errors.New("flush interval must be positive"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		problems = append(problems, errors.New("shutdown timeout must be positive"))
+	}
+	if c.BatchMaxIDs < 1 {
+		problems = append(problems, errors.New("batch max ids must be at least 1"))
+	}
+	for key, id := range c.APIKeys {
+		if key == "" || id <= 0 {
+			problems = append(problems, errors.New("API keys must be non-empty and map to a positive user ID"))
+			break
+		}
+	}
+	if len(c.RateLimitKeys) > 0 && c.RateLimitRPS <= 0 {
+		problems = append(problems, errors.New("per-key rate limits require a general rate limit (RATE_LIMIT_RPS)"))
+	}
+	for key, limit := range c.RateLimitKeys {
+		if _, ok := c.APIKeys[key]; !ok {
+			problems = append(problems, errors.New("per-key rate limits must refer to keys in API_KEYS"))
+			break
+		}
+		if limit.RPS <= 0 || limit.Burst < 1 {
+			problems = append(problems, errors.New("per-key rate limits must have a positive RPS and a burst of at least 1"))
+			break
+		}
+	}
+	for _, algorithm := range c.CompressionAlgorithms {
+		if algorithm != "br" && algorithm != "gzip" {
+			problems = append(problems, fmt.Errorf("compression algorithm %q must be \"br\", \"gzip\" or \"none\"", algorithm))
+		}
+	}
+	if c.CompressionLevel < 1 || c.CompressionLevel > 9 {
+		problems = append(problems, errors.New("compression level must be between 1 and 9"))
+	}
+	if c.ChaosLatencyMS < 0 {
+		problems = append(problems, errors.New("chaos latency must not be negative"))
+	}
+	if c.ChaosErrorRate < 0 || c.ChaosErrorRate > 1 {
+		problems = append(problems, errors.New("chaos error rate must be between 0 and 1"))
+	}
+	if c.DedupWindow < 0 {
+		problems = append(problems, errors.New("dedup window must not be negative (0 disables it)"))
+	}
+	if c.ResponseCacheTTL < 0 {
+		problems = append(problems, errors.New("response cache TTL must not be negative (0 disables the cache)"))
+	}
+	if c.ResponseCacheTTL > 0 && c.ResponseCacheSize < 1 {
+		problems = append(problems, errors.New("response cache size must be at least 1"))
+	}
+	if c.MinBodyReadRate < 0 {
+		problems = append(problems, errors.New("min body read rate must not be negative (0 disables it)"))
+	}
+	if c.BodyReadGrace < 0 {
+		problems = append(problems, errors.New("body read grace must not be negative"))
+	}
+	if c.LogLevel != "info" && c.LogLevel != "debug" {
+		problems = append(problems, fmt.Errorf("log level %q must be \"info\" or \"debug\"", c.LogLevel))
+	}
+	if c.LogRedact != redactOff && c.LogRedact != redactMask && c.LogRedact != redactHash {
+		problems = append(problems, fmt.Errorf("log redact %q must be \"off\", \"mask\" or \"hash\"", c.LogRedact))
+	}
+	if c.WatchInterval < 0 {
+		problems = append(problems, errors.New("watch interval must not be negative (0 disables watching the data file)"))
+	}
+	if c.ReloadPolicy != reloadReplace && c.ReloadPolicy != reloadMerge {
+		problems = append(problems, fmt.Errorf("reload policy %q must be \"replace\" or \"merge\"", c.ReloadPolicy))
+	}
+	if c.ValidationErrors != validationStructured && c.ValidationErrors != validationFlat {
+		problems = append(problems, fmt.Errorf("validation errors format %q must be \"structured\" or \"flat\"", c.ValidationErrors))
+	}
+	for route := range c.RouteHeaders {
+		if !strings.HasPrefix(route, "/") && !strings.Contains(route, " /") {
+			problems = append(problems, fmt.Errorf("route headers key %q must be \"METHOD /path\" or \"/path\"", route))
+		}
+	}
+	if c.RequestTimeout < 0 {
+		problems = append(problems, errors.New("request timeout must not be negative (0 disables it)"))
+	}
+	for route, timeout := range c.RouteTimeouts {
+		if !strings.HasPrefix(route, "/") && !strings.Contains(route, " /") {
+			problems = append(problems, fmt.Errorf("route timeouts key %q must be \"METHOD /path\" or \"/path\"", route))
+		}
+		if timeout < 0 {
+			problems = append(problems, fmt.Errorf("route timeout for %q must not be negative (0 disables it)", route))
+		}
+	}
+	for field, steps := range c.FieldNormalization {
+		if _, ok := normalizableFields[field]; !ok {
+			problems = append(problems, fmt.Errorf("field normalization for %q is not supported (use name, email or role)", field))
+		}
+		for _, step := range steps {
+			if _, ok := normalizeSteps[step]; !ok {
+				problems = append(problems, fmt.Errorf("field normalization step %q for %q is not supported (use trim, collapse_spaces or lowercase)", step, field))
+			}
+		}
+	}
+	for _, field := range c.UniqueFields {
+		if _, ok := uniqueUserFields[field]; !ok {
+			problems = append(problems, fmt.Errorf("unique field %q is not supported (use name or email)", field))
+		}
+	}
+	if !slices.Contains(c.UniqueFields, "email") {
+		problems = append(problems, errors.New("unique fields must include email"))
+	}
+	if c.HealthCheckTimeout <= 0 {
+		problems = append(problems, errors.New("health check timeout must be positive"))
+	}
+	if c.MaxBodyBytes < 1 {
+		problems = append(problems, errors.New("max body bytes must be at least 1"))
+	}
+	if c.BulkMaxBodyBytes < 1 {
+		problems = append(problems, errors.New("bulk max body bytes must be at least 1"))
+	}
+	if c.RetryAfter <= 0 {
+		problems = append(problems, errors.New("retry after must be positive"))
+	}
+	if c.MaxJSONDepth < 1 {
+		problems = append(problems, errors.New("max JSON depth must be at least 1"))
+	}
+	if c.BreakerThreshold < 0 {
+		problems = append(problems, errors.New("breaker threshold must not be negative (0 disables the circuit breaker)"))
+	}
+	if c.BreakerThreshold > 0 && c.BreakerCooldown <= 0 {
+		problems = append(problems, errors.New("breaker cooldown must be positive"))
+	}
+	if c.StoreRetryAttempts < 1 {
+		problems = append(problems, errors.New("store retry attempts must be at least 1"))
+	}
+	if c.StoreRetryBaseDelay <= 0 {
+		problems = append(problems, errors.New("store retry base delay must be positive"))
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		problems = append(problems, errors.New("TLS cert file and key file must be set together"))
+	}
+	if c.HTTPRedirectPort != "" {
+		if c.TLSCertFile == "" {
+			problems = append(problems, errors.New("HTTP redirect port requires TLS cert and key files"))
+		}
+		if port, err := strconv.Atoi(c.HTTPRedirectPort); err != nil || port < 1 || port > 65535 || c.HTTPRedirectPort == c.Port {
+			problems = append(problems, fmt.Errorf("HTTP redirect port %q must be a number between 1 and 65535 different from the service port", c.HTTPRedirectPort))
+		}
+	}
+	if c.HSTSMaxAge < 0 {
+		problems = append(problems, errors.New("HSTS max age must not be negative (0 disables HSTS)"))
+	}
+	if len(c.TLSCipherSuites) > 0 && c.TLSMinVersion >= tls.VersionTLS13 {
+		problems = append(problems, errors.New("TLS cipher suites cannot be configured when the minimum version is 1.3"))
+	}
+	if strings.ContainsAny(c.DefaultEmailDomain, "@ ") {
+		problems = append(problems, fmt.Errorf("default email domain %q must be a bare domain such as example.com", c.DefaultEmailDomain))
+	}
+	if c.ReplayProtection && (c.MaxClockSkew <= 0 || c.NonceTTL < 2*c.MaxClockSkew) {
+		problems = append(problems, errors.New("with replay protection, max clock skew must be positive and nonce TTL at least twice the skew, or a nonce could be replayed inside the skew window"))
+	}
+	for _, flag := range c.FeatureFlags {
+		if !knownFeatureFlags[flag] {
+			problems = append(problems, fmt.Errorf("feature flag %q is not known", flag))
+		}
+	}
+	if c.JSONPoolMaxBuffer < 0 {
+		problems = append(problems, errors.New("JSON pool max buffer must not be negative (0 disables pooling)"))
+	}
+	if c.HistoryLimit < 0 {
+		problems = append(problems, errors.New("history limit must not be negative (0 disables it)"))
+	}
+	if c.MaxQueryParams < 1 {
+		problems = append(problems, errors.New("max query params must be at least 1"))
+	}
+	if c.LogStreamBuffer < 1 {
+		problems = append(problems, errors.New("log stream buffer must be at least 1"))
+	}
+	if c.DefaultRole != "" && !slices.Contains(allowedRoles, c.DefaultRole) {
+		problems = append(problems, fmt.Errorf("default role %q must be one of: %s", c.DefaultRole, strings.Join(allowedRoles, ", ")))
+	}
+	for _, domain := range c.AllowedEmailDomains {
+		if strings.ContainsAny(domain, "@ ") {
+			problems = append(problems, fmt.Errorf("allowed email domain %q must be a bare domain such as example.com", domain))
+		}
+	}
+
+	return problems
+}
+
+// Sobrescribir un valor de texto si la variable tiene contenido
+func setString(dst *string, value string) {
+	if value != "" {
+		*dst = value
+	}
+}
+
+// Añadir o sobrescribir plazos por ruta a partir de sus duraciones en texto
+func setRouteTimeouts(dst map[string]time.Duration, name string, values map[string]string, problems *[]error) {
+	for route, value := range values {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			*problems = append(*problems, fmt.Errorf("%s entry %q has an invalid duration %q (e.g. 30s, 5m)", name, route, value))
+			continue
+		}
+		dst[route] = d
+	}
+}
+
+// Sustituir los pasos de normalización de los campos indicados
+func setFieldNormalization(dst map[string][]string, values map[string][]string) {
+	for field, steps := range values {
+		dst[field] = steps
+	}
+}
+
+// Sobrescribir una duración si la variable tiene contenido
+func setDuration(dst *time.Duration, name, value string, problems *[]error) {
+	if value == "" {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		*problems = append(*problems, fmt.Errorf("%s %q is not a valid duration (e.g. 500ms, 2s)", name, value))
+		return
+	}
+	*dst = d
+}
+
+// Sobrescribir un número decimal si la variable tiene contenido
+func setFloat(dst *float64, name, value string, problems *[]error) {
+	if value == "" {
+		return
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		*problems = append(*problems, fmt.Errorf("%s %q is not a valid number", name, value))
+		return
+	}
+	*dst = f
+}
+
+// Sobrescribir un número entero si la variable tiene contenido
+func setInt(dst *int, name, value string, problems *[]error) {
+	if value == "" {
+		return
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		*problems = append(*problems, fmt.Errorf("%s %q is not a valid integer", name, value))
+		return
+	}
+	*dst = n
+}
+
+// Sobrescribir un entero de 64 bits si la variable tiene contenido
+func setInt64(dst *int64, name, value string, problems *[]error) {
+	if value == "" {
+		return
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		*problems = append(*problems, fmt.Errorf("%s %q is not a valid integer", name, value))
+		return
+	}
+	*dst = n
+}
+
+// Sobrescribir un booleano si la variable tiene contenido
+func setBool(dst *bool, name, value string, problems *[]error) {
+	if value == "" {
+		return
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		*problems = append(*problems, fmt.Errorf("%s %q is not a valid boolean", name, value))
+		return
+	}
+	*dst = b
+}
+
+// Sobrescribir una lista de rangos CIDR; una IP suelta se trata como /32 o /128
+func setPrefixes(dst *[]netip.Prefix, name string, values []string, problems *[]error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(value)
+			if addrErr != nil {
+				*problems = append(*problems, fmt.Errorf("%s entry %q is not a valid CIDR range or IP", name, value))
+				continue
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	*dst = prefixes
+}
+
+// Leer API keys con el formato "clave:idUsuario,clave:idUsuario"
+func setAPIKeys(dst *map[string]int, value string, problems *[]error) {
+	keys := make(map[string]int)
+	for _, pair := range strings.Split(value, ",") {
+		key, rawID, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		id, err := strconv.Atoi(rawID)
+		if !ok || err != nil {
+			*problems = append(*problems, errors.New("API_KEYS entries must have the form key:userID"))
+			return
+		}
+		keys[key] = id
+	}
+	*dst = keys
+}
+
+// Leer límites por API key con el formato "clave:rps:ráfaga,clave:rps:ráfaga"
+func setRateLimitKeys(dst *map[string]RateLimit, value string, problems *[]error) {
+	limits := make(map[string]RateLimit)
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) != 3 {
+			*problems = append(*problems, errors.New("RATE_LIMIT_KEYS entries must have the form key:rps:burst"))
+			return
+		}
+		rps, rpsErr := strconv.ParseFloat(parts[1], 64)
+		burst, burstErr := strconv.Atoi(parts[2])
+		if rpsErr != nil || burstErr != nil {
+			*problems = append(*problems, errors.New("RATE_LIMIT_KEYS entries must have the form key:rps:burst"))
+			return
+		}
+		limits[parts[0]] = RateLimit{RPS: rps, Burst: burst}
+	}
+	*dst = limits
+}
+
+// Versiones de TLS aceptadas como mínimo
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Sobrescribir la versión mínima de TLS ("1.2" o "1.3") si hay valor
+func setTLSVersion(dst *uint16, name, value string, problems *[]error) {
+	if value == "" {
+		return
+	}
+	version, ok := tlsVersions[strings.TrimSpace(value)]
+	if !ok {
+		*problems = append(*problems, fmt.Errorf("%s %q must be \"1.2\" or \"1.3\"", name, value))
+		return
+	}
+	*dst = version
+}
+
+// Sobrescribir las cipher suites a partir de sus nombres IANA; solo se
+// aceptan las que Go considera seguras
+func setCipherSuites(dst *[]uint16, name string, values []string, problems *[]error) {
+	byName := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		byName[suite.Name] = suite.ID
+	}
+
+	suites := []uint16{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		id, ok := byName[value]
+		if !ok {
+			*problems = append(*problems, fmt.Errorf("%s entry %q is not a supported secure cipher suite", name, value))
+			continue
+		}
+		suites = append(suites, id)
+	}
+	*dst = suites
+}
+
+// Configuración TLS del servidor según la política configurada
+func (c Config) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:   c.TLSMinVersion,
+		CipherSuites: c.TLSCipherSuites,
+	}
+}
+
+// Normalizar una lista de dominios a minúsculas, sin entradas vacías
+func parseDomains(values []string) []string {
+	domains := []string{}
+	for _, value := range values {
+		if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+			domains = append(domains, value)
+		}
+	}
+	return domains
+}
+
+// Normalizar una lista de nombres de campo, sin vacíos ni repetidos
+func parseFieldNames(values []string) []string {
+	fields := []string{}
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value != "" && !slices.Contains(fields, value) {
+			fields = append(fields, value)
+		}
+	}
+	return fields
+}
+
+// Normalizar la lista de algoritmos de compresión; "none" la deja vacía
+func parseCompression(values []string) []string {
+	algorithms := []string{}
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value != "" && value != "none" {
+			algorithms = append(algorithms, value)
+		}
+	}
+	return algorithms
+}
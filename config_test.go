@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigFileRejectsUnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"port":"9090","rate_limt_rps":5}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+
+	cfg, err := LoadConfig()
+	if err == nil {
+		t.Fatal("LoadConfig accepted a config file with a misspelled key")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "rate_limt_rps") || !strings.Contains(msg, path) {
+		t.Errorf("error %q does not name the unknown key and the file", msg)
+	}
+	// El resto del archivo se aplica para señalar todos los problemas
+	if cfg.Port != "9090" {
+		t.Errorf("port %q, want the valid keys still applied", cfg.Port)
+	}
+}
+
+func TestConfigFileRejectsTrailingData(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"port":"9090"} {"port":"9091"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+
+	if _, err := LoadConfig(); err == nil {
+		t.Error("LoadConfig accepted a config file with data after the object")
+	}
+}
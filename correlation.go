@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Claves de contexto con los datos de correlación de la petición
+const (
+	requestIDKey contextKey = "request_id"
+	traceKey     contextKey = "trace"
+)
+
+// IDs de petición aceptados del cliente; el resto se sustituye por uno nuevo
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// traceparent de W3C Trace Context: versión-traceid-parentid-flags
+var validTraceparent = regexp.MustCompile(`^00-([0-9a-f]{32})-[0-9a-f]{16}-([0-9a-f]{2})$`)
+
+// Contexto de traza entrante
+type traceContext struct {
+	traceID    string
+	flags      string
+	traceState string
+}
+
+// Generar un identificador aleatorio de n bytes en hexadecimal
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Middleware que asigna un X-Request-ID a cada petición (reutilizando el del
+// cliente si es válido), lo devuelve en la respuesta y guarda en el contexto
+// el ID y el traceparent entrante para propagarlos a otros servicios
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID.MatchString(id) {
+			id = randomHex(16)
+		}
+		w.Header().Set("X-Request-ID", id)
+
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+		if m := validTraceparent.FindStringSubmatch(r.Header.Get("traceparent")); m != nil {
+			ctx = context.WithValue(ctx, traceKey, traceContext{
+				traceID:    m[1],
+				flags:      m[2],
+				traceState: r.Header.Get("tracestate"),
+			})
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Obtener el ID de la petición en curso
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// Transport que añade a las peticiones salientes el X-Request-ID y el
+// contexto de traza de la petición entrante guardados en su contexto
+type correlationTransport struct {
+	base http.RoundTripper
+}
+
+func (t correlationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := requestIDFromContext(req.Context())
+	trace, hasTrace := req.Context().Value(traceKey).(traceContext)
+	if id == "" && !hasTrace {
+		return t.base.RoundTrip(req)
+	}
+
+	// RoundTrip no debe modificar la petición original
+	req = req.Clone(req.Context())
+	if id != "" && req.Header.Get("X-Request-ID") == "" {
+		req.Header.Set("X-Request-ID", id)
+	}
+	if hasTrace && req.Header.Get("traceparent") == "" {
+		// Mismo trace ID con un nuevo span ID para la llamada saliente
+		req.Header.Set("traceparent", strings.Join([]string{"00", trace.traceID, randomHex(8), trace.flags}, "-"))
+		if trace.traceState != "" {
+			req.Header.Set("tracestate", trace.traceState)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// Cliente HTTP para llamar a otros servicios. Los handlers deben crear la
+// petición con el contexto de la entrante para que se propague la
+// correlación:
+//
+//	req, err := http.NewRequestWithContext(r.Context(), "GET", url, nil)
+//	resp, err := outboundClient.Do(req)
+var outboundClient = &http.Client{
+	Transport: correlationTransport{base: http.DefaultTransport},
+	Timeout:   10 * time.Second,
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// Marca de orden de bytes UTF-8
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// Decodificar el cuerpo JSON de la petición en dst; si falla escribe la
+// respuesta de error y devuelve false
+func (srv *server) decodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	body, ok := srv.readJSONBody(w, r)
+	return ok && srv.unmarshalJSONBody(w, r, body, dst)
+}
+
+// Leer el cuerpo y comprobar que es un documento JSON aceptable sin
+// decodificarlo todavía
+func (srv *server) readJSONBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		srv.writeBodyReadError(w, r, err)
+		return nil, false
+	}
+	// Algunas herramientas de Windows anteponen un BOM UTF-8 que el
+	// decodificador JSON no acepta
+	body = bytes.TrimPrefix(body, utf8BOM)
+
+	if exceedsJSONDepth(body, srv.config.MaxJSONDepth) {
+		srv.writeError(w, r, http.StatusBadRequest, codeInvalidJSON, "JSON nesting exceeds the maximum depth of %d", srv.config.MaxJSONDepth)
+		return nil, false
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	var doc json.RawMessage
+	if err := dec.Decode(&doc); err != nil {
+		srv.writeError(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid JSON format")
+		return nil, false
+	}
+	if hasTrailingData(dec) {
+		srv.writeError(w, r, http.StatusBadRequest, codeInvalidJSON, "Request body must contain a single JSON object")
+		return nil, false
+	}
+
+	key, err := findDuplicateKey(body)
+	if err != nil {
+		srv.writeError(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid JSON format")
+		return nil, false
+	}
+	if key != "" {
+		srv.writeError(w, r, http.StatusBadRequest, codeInvalidJSON, "Duplicate JSON key %q", key)
+		return nil, false
+	}
+	return body, true
+}
+
+// Comprobar si queda algo tras el primer valor JSON; More no detecta un
+// '}' o ']' sobrante, así que también se pide el siguiente token
+func hasTrailingData(dec *json.Decoder) bool {
+	if dec.More() {
+		return true
+	}
+	_, err := dec.Token()
+	return err != io.EOF
+}
+
+// Decodificar un cuerpo ya leído con readJSONBody
+func (srv *server) unmarshalJSONBody(w http.ResponseWriter, r *http.Request, body []byte, dst interface{}) bool {
+	if err := json.Unmarshal(body, dst); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			expected, got := expectedJSONKind(dst), jsonKindOf(body)
+			if expected != "" && got != expected {
+				srv.writeRootTypeError(w, r, expected, got)
+				return false
+			}
+		}
+		srv.writeError(w, r, http.StatusBadRequest, codeInvalidJSON, "Invalid JSON format")
+		return false
+	}
+	return true
+}
+
+// Tipo JSON raíz con artículo, para los mensajes de tipo equivocado
+var jsonKindLabels = map[string]string{
+	"object":  "an object",
+	"array":   "an array",
+	"string":  "a string",
+	"number":  "a number",
+	"boolean": "a boolean",
+	"null":    "null",
+}
+
+// Endpoint al que probablemente iba dirigido un cuerpo con el tipo raíz
+// equivocado, por método y ruta; solo se sugiere si se recibió un objeto
+// donde se esperaba un array o al revés
+var rootTypeHints = map[string]string{
+	"POST /api/users":      "To create several users, send the array to POST /api/users/bulk",
+	"POST /api/users/bulk": "To create a single user, send the object to POST /api/users",
+	"PUT /api/users/bulk":  "To update a single user, send the object to PUT /api/users/{id}",
+}
+
+// Tipo JSON raíz que espera dst ("object" o "array"), o "" si no es ninguno
+func expectedJSONKind(dst interface{}) string {
+	switch reflect.TypeOf(dst).Elem().Kind() {
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return ""
+}
+
+// Tipo JSON raíz de un documento válido según su primer carácter
+func jsonKindOf(body []byte) string {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return ""
+	}
+	switch body[0] {
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	}
+	return "number"
+}
+
+// Responder 400 cuando el cuerpo es JSON válido pero de otro tipo raíz, con
+// una pista del endpoint correcto si la hay
+func (srv *server) writeRootTypeError(w http.ResponseWriter, r *http.Request, expected, got string) {
+	w.Header().Set("Content-Language", requestLanguage(r))
+	w.Header().Add("Vary", "Accept-Language")
+
+	resp := Response{
+		Status:  "error",
+		Message: localize(r, "Expected a JSON "+expected+", got %s", messageKey(jsonKindLabels[got])),
+		Code:    codeInvalidJSON,
+	}
+	template, _ := routeTemplate(r)
+	if hint, ok := rootTypeHints[r.Method+" "+template]; ok && (got == "object" || got == "array") {
+		resp.Data = map[string]string{"hint": localize(r, hint)}
+	}
+	srv.writeJSON(w, http.StatusBadRequest, resp)
+}
+
+// Decodificar un valor JSON genérico conservando los números como
+// json.Number, para que los enteros grandes no pierdan precisión al pasar
+// por float64; quien los use debe convertirlos y validarlos explícitamente
+func decodeJSONValue(data []byte, v *interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// Comparar dos valores decodificados con decodeJSONValue. Los números se
+// comparan por su valor exacto, así que 1, 1.0 y 1e0 son iguales
+// (RFC 6902 §4.6); el resto de tipos, por su contenido.
+func jsonValuesEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, okA := new(big.Rat).SetString(a.String())
+		y, okB := new(big.Rat).SetString(b.String())
+		return okA && okB && x.Cmp(y) == 0
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonValuesEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			other, exists := b[key]
+			if !exists || !jsonValuesEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+// Recorrer los tokens del documento para comprobar si algún contenedor
+// supera el anidamiento permitido, sin decodificar los valores. Los errores
+// de sintaxis se dejan para la decodificación posterior.
+func exceedsJSONDepth(data []byte, limit int) bool {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			if depth > limit {
+				return true
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
+
+// Recorrer los tokens del documento y devolver la primera clave repetida
+// dentro de un mismo objeto, o "" si no hay ninguna. Se comparan sin
+// distinguir mayúsculas, como encoding/json al asignar campos: con "email"
+// y "Email" ganaría el último sin avisar.
+func findDuplicateKey(data []byte) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	// Una entrada por contenedor abierto; nil para los arrays
+	var stack []map[string]bool
+	expectKey := false
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch t := tok.(type) {
+		case json.Delim:
+			switch t {
+			case '{':
+				stack = append(stack, map[string]bool{})
+				expectKey = true
+				continue
+			case '[':
+				stack = append(stack, nil)
+			case '}', ']':
+				stack = stack[:len(stack)-1]
+			}
+		case string:
+			if expectKey {
+				keys, folded := stack[len(stack)-1], strings.ToLower(t)
+				if keys[folded] {
+					return t, nil
+				}
+				keys[folded] = true
+				expectKey = false
+				continue
+			}
+		}
+
+		// Tras un valor completo dentro de un objeto viene otra clave
+		expectKey = len(stack) > 0 && stack[len(stack)-1] != nil
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestBodyWithUTF8BOM(t *testing.T) {
+	_, ts := newTestServer(t, memoryStore{}, nil)
+	bom := string(utf8BOM)
+
+	resp, data := doRequest(t, ts, "POST", "/api/users", bom+`{"name":"Ana Ruiz","email":"ana@example.com"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("POST /api/users with BOM: status %d, want 201: %s", resp.StatusCode, data)
+	}
+
+	// El lote se decodifica en streaming por otro camino
+	resp, data = doRequest(t, ts, "POST", "/api/users/bulk", bom+`[{"name":"Bea Ruiz","email":"bea@example.com"}]`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("POST /api/users/bulk with BOM: status %d, want 201: %s", resp.StatusCode, data)
+	}
+
+	// Solo se admite al principio
+	resp, _ = doRequest(t, ts, "POST", "/api/users", `{"name":"Eva Ruiz",`+bom+`"email":"eva@example.com"}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("POST /api/users with a BOM inside the body: status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestDuplicateKeysDifferingOnlyInCase(t *testing.T) {
+	_, ts := newTestServer(t, memoryStore{}, nil)
+
+	resp, data := doRequest(t, ts, "POST", "/api/users", `{"name":"Ana Ruiz","email":"ana@example.com","Email":"eva@example.com"}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("POST with email and Email: status %d, want 400: %s", resp.StatusCode, data)
+	}
+	if got := decodeResponse(t, data).Code; got != codeInvalidJSON {
+		t.Errorf("code %q, want %q", got, codeInvalidJSON)
+	}
+
+	// También dentro de un lote
+	resp, data = doRequest(t, ts, "POST", "/api/users/bulk", `[{"name":"Bea Ruiz","EMAIL":"bea@example.com","email":"eva@example.com"}]`)
+	if resp.StatusCode == http.StatusCreated {
+		t.Errorf("bulk item with EMAIL and email was accepted: %s", data)
+	}
+
+	// La misma clave en objetos distintos no es una repetición
+	resp, data = doRequest(t, ts, "POST", "/api/users/bulk", `[{"name":"Ana Ruiz","email":"ana@example.com"},{"name":"Eva Ruiz","Email":"eva@example.com"}]`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("bulk with one email key per item: status %d, want 201: %s", resp.StatusCode, data)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// El cuerpo comprimido con gzip está corrupto o truncado
+var errInvalidGzip = errors.New("request body is not valid gzip")
+
+// Cuerpo gzip descomprimido al vuelo; traduce los errores de formato a
+// errInvalidGzip para que writeBodyReadError responda 400
+type gzipBody struct {
+	gz   *gzip.Reader
+	body io.ReadCloser
+}
+
+func (g *gzipBody) Read(p []byte) (int, error) {
+	n, err := g.gz.Read(p)
+	var corrupt flate.CorruptInputError
+	if errors.Is(err, gzip.ErrChecksum) || errors.Is(err, gzip.ErrHeader) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &corrupt) {
+		err = errInvalidGzip
+	}
+	return n, err
+}
+
+func (g *gzipBody) Close() error {
+	g.gz.Close()
+	return g.body.Close()
+}
+
+// Middleware que descomprime los cuerpos con Content-Encoding: gzip antes de
+// que los lean los handlers. El límite de tamaño se aplica dos veces: al
+// cuerpo comprimido (max_body) y al descomprimido, para que una bomba zip no
+// pueda generar más datos de los que se aceptarían sin comprimir. Cualquier
+// otra codificación responde 415.
+func (srv *server) requestDecompressMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+		switch encoding {
+		case "", "identity":
+			next.ServeHTTP(w, r)
+			return
+		case "gzip", "x-gzip":
+		default:
+			srv.writeError(w, r, http.StatusUnsupportedMediaType, codeUnsupportedEncoding, "Unsupported Content-Encoding %q: only gzip is accepted", encoding)
+			return
+		}
+
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			if errors.Is(err, gzip.ErrHeader) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				err = errInvalidGzip
+			}
+			srv.writeBodyReadError(w, r, err)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, &gzipBody{gz: gz, body: r.Body}, srv.bodyLimit(r))
+		r.Header.Del("Content-Encoding")
+		r.ContentLength = -1
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Respuesta de la primera petición de un grupo de duplicados; done se
+// cierra cuando ya está completa
+type dedupEntry struct {
+	done    chan struct{}
+	expires time.Time
+	status  int
+	header  http.Header
+	body    []byte
+}
+
+// Peticiones de creación recientes por huella (DEDUP_WINDOW)
+type dedupCache struct {
+	mu        sync.Mutex
+	window    time.Duration
+	entries   map[string]*dedupEntry
+	lastPurge time.Time
+}
+
+func newDedupCache(window time.Duration) *dedupCache {
+	return &dedupCache{window: window, entries: make(map[string]*dedupEntry)}
+}
+
+// Devolver la entrada vigente de la huella, o crear una nueva si no la hay;
+// first indica que la petición actual es la que debe ejecutarse
+func (c *dedupCache) claim(key string, now time.Time) (entry *dedupEntry, first bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Sub(c.lastPurge) > c.window {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.lastPurge = now
+	}
+
+	if e, ok := c.entries[key]; ok && now.Before(e.expires) {
+		return e, false
+	}
+	e := &dedupEntry{done: make(chan struct{}), expires: now.Add(c.window)}
+	c.entries[key] = e
+	return e, true
+}
+
+// Olvidar una entrada para que la siguiente petición se ejecute de nuevo
+func (c *dedupCache) forget(key string, entry *dedupEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[key] == entry {
+		delete(c.entries, key)
+	}
+}
+
+// Huella de una creación: quién llama, ruta y cuerpo canónico, para que
+// cambios de espacios u orden de claves no cuenten como peticiones distintas
+func (srv *server) dedupKey(r *http.Request, body []byte) string {
+	canonical := body
+	var doc interface{}
+	if err := decodeJSONValue(bytes.TrimPrefix(body, utf8BOM), &doc); err == nil {
+		if encoded, err := json.Marshal(doc); err == nil {
+			canonical = encoded
+		}
+	}
+	sum := sha256.New()
+	for _, part := range [][]byte{[]byte(srv.rateLimitKey(r)), []byte(r.Method + " " + r.URL.Path), canonical} {
+		sum.Write(part)
+		sum.Write([]byte{0})
+	}
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// Middleware que absorbe los envíos duplicados de POST /api/users: si llega
+// un cuerpo idéntico del mismo cliente dentro de DEDUP_WINDOW se responde
+// con la respuesta de la primera petición, esperándola si aún está en curso,
+// sin crear otro usuario. A diferencia de una clave de idempotencia, no
+// requiere nada del cliente, pero solo cubre una ventana corta y no distingue
+// dos creaciones idénticas hechas a propósito dentro de ella. Los errores
+// 5xx no se recuerdan para que se puedan reintentar.
+func (srv *server) dedupMiddleware(cache *dedupCache) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			template, _ := routeTemplate(r)
+			if r.Method != "POST" || template != "/api/users" || r.Body == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				srv.writeBodyReadError(w, r, err)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			key := srv.dedupKey(r, body)
+			entry, first := cache.claim(key, time.Now())
+			if !first {
+				select {
+				case <-entry.done:
+				case <-r.Context().Done():
+					return
+				}
+				for name, values := range entry.header {
+					w.Header()[name] = values
+				}
+				w.Header().Set("X-Deduplicated", "true")
+				w.WriteHeader(entry.status)
+				w.Write(entry.body)
+				return
+			}
+
+			before := w.Header().Clone()
+			rw := &recordingWriter{ResponseWriter: w}
+			defer func() {
+				// También si el handler entra en pánico, para no dejar
+				// esperando a los duplicados
+				entry.status = rw.status
+				if entry.status == 0 {
+					entry.status = http.StatusInternalServerError
+				}
+				entry.header = http.Header{}
+				for name, values := range w.Header() {
+					if !slices.Equal(before[name], values) {
+						entry.header[name] = slices.Clone(values)
+					}
+				}
+				entry.body = rw.body.Bytes()
+				if entry.status >= 500 {
+					cache.forget(key, entry)
+				}
+				close(entry.done)
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
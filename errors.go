@@ -0,0 +1,49 @@
+package main
+
+import "net/http"
+
+// Códigos de error legibles por máquina que acompañan a cada respuesta de
+// error en el campo "code".
+//
+// 400 Bad Request se reserva para peticiones que no se pueden interpretar:
+// JSON mal formado, claves duplicadas, cuerpos que no son UTF-8 o
+// parámetros de ruta/consulta inválidos. 422 Unprocessable Entity indica
+// que el cuerpo es JSON válido pero sus datos no cumplen las reglas de
+// validación (campos obligatorios, formato de email, rol, etc.).
+const (
+	codeBadRequest          = "bad_request"
+	codeInvalidJSON         = "invalid_json"
+	codeInvalidID           = "invalid_id"
+	codeValidationFailed    = "validation_failed"
+	codeImmutableField      = "immutable_field"
+	codeTestFailed          = "test_failed"
+	codeConflict            = "conflict"
+	codeUnauthorized        = "unauthorized"
+	codeForbidden           = "forbidden"
+	codeNotFound            = "not_found"
+	codeRateLimited         = "rate_limited"
+	codeRequestTimeout      = "request_timeout"
+	codePayloadTooLarge     = "payload_too_large"
+	codeUnsupportedEncoding = "unsupported_encoding"
+	codeStoreUnavailable    = "store_unavailable"
+	codeMaintenance         = "maintenance"
+	codeInternalError       = "internal_error"
+)
+
+// Error con el estado HTTP y el código que le corresponden, para funciones
+// que detectan el error sin escribir la respuesta
+type apiError struct {
+	status int
+	code   string
+	format string
+	args   []interface{}
+}
+
+func newAPIError(status int, code, format string, args ...interface{}) *apiError {
+	return &apiError{status: status, code: code, format: format, args: args}
+}
+
+// Responder con un apiError
+func (srv *server) writeAPIError(w http.ResponseWriter, r *http.Request, err *apiError) {
+	srv.writeError(w, r, err.status, err.code, err.format, err.args...)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filas que se escriben entre cada Flush
+const exportBatchSize = 100
+
+// Plazo de escritura de cada lote; se renueva antes de cada uno para que
+// un cliente lento no pueda bloquear la exportación indefinidamente
+const exportBatchWriteTimeout = 30 * time.Second
+
+// Escritor de un formato de exportación
+type exportWriter interface {
+	// Escribir un usuario; el formato decide si lo almacena en un buffer
+	write(user User)
+	// Vaciar el buffer propio antes de cada Flush de la respuesta
+	flush()
+}
+
+// Exportación en CSV con cabecera
+type csvExportWriter struct {
+	cw *csv.Writer
+}
+
+func newCSVExportWriter(w http.ResponseWriter) *csvExportWriter {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "name", "email", "role", "email_verified", "created_at", "updated_at", "deleted_at", "tags"})
+	return &csvExportWriter{cw: cw}
+}
+
+func (e *csvExportWriter) write(user User) {
+	deletedAt := ""
+	if user.DeletedAt != nil {
+		deletedAt = user.DeletedAt.Format(time.RFC3339)
+	}
+	e.cw.Write([]string{
+		strconv.Itoa(user.ID),
+		user.Name,
+		user.Email,
+		user.Role,
+		strconv.FormatBool(user.EmailVerified),
+		user.CreatedAt.Format(time.RFC3339),
+		user.UpdatedAt.Format(time.RFC3339),
+		deletedAt,
+		strings.Join(user.Tags, ";"),
+	})
+}
+
+func (e *csvExportWriter) flush() { e.cw.Flush() }
+
+// Exportación en NDJSON: un objeto JSON por línea
+type ndjsonExportWriter struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonExportWriter) write(user User) { e.enc.Encode(user) }
+func (e *ndjsonExportWriter) flush()          {}
+
+// Exportar los usuarios en CSV o, con ?format=ndjson, en NDJSON. La
+// respuesta se envía en streaming (chunked, al no llevar Content-Length)
+// lote a lote y respeta los mismos filtros que el listado. Las filas van
+// ordenadas por ID, así que una exportación interrumpida se reanuda con
+// ?cursor=<último id recibido>. Con ?page y ?per_page se exporta solo esa
+// página, contada a partir del cursor.
+func (srv *server) exportUsersHandler(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "csv" && format != "ndjson" {
+		srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "Invalid format: must be csv or ndjson")
+		return
+	}
+
+	cursor := 0
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 0 {
+			srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "Invalid cursor: must be a non-negative user ID")
+			return
+		}
+		cursor = parsed
+	}
+
+	filter, ok := srv.parseUserFilter(w, r)
+	if !ok {
+		return
+	}
+	page, perr := srv.parsePagination(r)
+	if perr != nil {
+		srv.writeAPIError(w, r, perr)
+		return
+	}
+
+	// Copia para no mantener el bloqueo mientras se escribe
+	srv.usersMu.RLock()
+	snapshot := slices.DeleteFunc(slices.Clone(srv.users), func(u User) bool { return !filter.match(u) })
+	srv.usersMu.RUnlock()
+
+	slices.SortFunc(snapshot, func(a, b User) int { return a.ID - b.ID })
+	start, _ := slices.BinarySearchFunc(snapshot, cursor+1, func(u User, id int) int { return u.ID - id })
+	snapshot = applyPagination(w, r, page, snapshot[start:])
+
+	var out exportWriter
+	if format == "ndjson" {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="users.ndjson"`)
+		out = &ndjsonExportWriter{enc: json.NewEncoder(w)}
+	} else {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="users.csv"`)
+		out = newCSVExportWriter(w)
+	}
+
+	rc := http.NewResponseController(w)
+	for i, user := range snapshot {
+		if i%exportBatchSize == 0 {
+			if srv.clientGone(r) {
+				return
+			}
+			out.flush()
+			rc.Flush()
+			rc.SetWriteDeadline(time.Now().Add(exportBatchWriteTimeout))
+		}
+		out.write(user)
+	}
+	out.flush()
+	rc.Flush()
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Flags de funcionalidad conocidas. Una flag que no está aquí se ignora, así
+// que al añadir un camino nuevo detrás de una flag hay que registrarla.
+const flagStrictQuery = "strict-query"
+
+var knownFeatureFlags = map[string]bool{
+	flagStrictQuery: true,
+}
+
+// Clave de contexto con las flags activas de la petición
+const featureFlagsKey contextKey = "feature_flags"
+
+// Flags activas por defecto: las de FEATURE_FLAGS más las que activan otras
+// opciones de configuración
+func (srv *server) defaultFeatureFlags() []string {
+	flags := slices.Clone(srv.config.FeatureFlags)
+	if srv.config.StrictQueryParams && !slices.Contains(flags, flagStrictQuery) {
+		flags = append(flags, flagStrictQuery)
+	}
+	return flags
+}
+
+// Aplicar a las flags por defecto las de X-Feature-Flags: "nombre" activa
+// una flag y "-nombre" desactiva una que esté activa por defecto
+func (srv *server) resolveFeatureFlags(defaults []string, header string) []string {
+	enabled := slices.Clone(defaults)
+	for _, part := range strings.Split(header, ",") {
+		name := strings.ToLower(strings.TrimSpace(part))
+		disable := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+		if name == "" {
+			continue
+		}
+		if !knownFeatureFlags[name] {
+			srv.debugf("ignoring unknown feature flag %q", name)
+			continue
+		}
+		if disable {
+			enabled = slices.DeleteFunc(enabled, func(flag string) bool { return flag == name })
+		} else if !slices.Contains(enabled, name) {
+			enabled = append(enabled, name)
+		}
+	}
+	sort.Strings(enabled)
+	return enabled
+}
+
+// Indica si la flag está activa para la petición de ctx; fuera de una
+// petición solo cuentan las flags por defecto
+func (srv *server) FeatureEnabled(ctx context.Context, name string) bool {
+	flags, ok := ctx.Value(featureFlagsKey).([]string)
+	if !ok {
+		flags = srv.defaultFeatureFlags()
+	}
+	return slices.Contains(flags, name)
+}
+
+// Middleware que guarda en el contexto las flags activas de la petición y
+// las devuelve en X-Feature-Flags-Applied
+func (srv *server) featureFlagsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flags := srv.resolveFeatureFlags(srv.defaultFeatureFlags(), r.Header.Get("X-Feature-Flags"))
+		w.Header().Add("Vary", "X-Feature-Flags")
+		if len(flags) > 0 {
+			w.Header().Set("X-Feature-Flags-Applied", strings.Join(flags, ","))
+		}
+		ctx := context.WithValue(r.Context(), featureFlagsKey, flags)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
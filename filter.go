@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filtros de consulta compartidos por el listado y la exportación
+type userFilter struct {
+	// Con modifiedSince solo se incluyen los cambios posteriores, tombstones
+	// incluidos; sin él se omiten los usuarios eliminados
+	hasModifiedSince bool
+	modifiedSince    time.Time
+	// ?verified=true/false filtra por verificación del email
+	verified *bool
+	// ?has_<campo>=true/false filtra por si un campo opcional tiene valor;
+	// has_role=false lista los usuarios sin rol
+	has map[string]bool
+	// ?tag=<etiqueta> y ?tag_match=all|any
+	tags tagFilter
+}
+
+// Campos opcionales que admiten el filtro has_<campo> y cómo saber si
+// tienen valor
+var optionalUserFields = map[string]func(User) bool{
+	"role": func(u User) bool { return u.Role != "" },
+	"tags": func(u User) bool { return len(u.Tags) > 0 },
+}
+
+// Leer los filtros de la query; si alguno es inválido escribe el error
+func (srv *server) parseUserFilter(w http.ResponseWriter, r *http.Request) (userFilter, bool) {
+	var filter userFilter
+	query := r.URL.Query()
+
+	// Acotar el trabajo por petición: cada parámetro de filtro, repetido o
+	// no, cuenta; la paginación y el formato no añaden trabajo de filtrado
+	count := 0
+	for _, name := range userFilterParams() {
+		count += len(query[name])
+	}
+	if count > srv.config.MaxQueryParams {
+		srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "Too many filter parameters: at most %d are allowed", srv.config.MaxQueryParams)
+		return filter, false
+	}
+
+	if query.Has("modified_since") {
+		parsed, err := time.Parse(time.RFC3339Nano, query.Get("modified_since"))
+		if err != nil {
+			srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "Invalid modified_since: must be an RFC 3339 timestamp")
+			return filter, false
+		}
+		filter.hasModifiedSince = true
+		filter.modifiedSince = parsed
+	}
+
+	if query.Has("verified") {
+		verified, err := strconv.ParseBool(query.Get("verified"))
+		if err != nil {
+			srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "Invalid verified: must be true or false")
+			return filter, false
+		}
+		filter.verified = &verified
+	}
+
+	for field := range optionalUserFields {
+		param := "has_" + field
+		if !query.Has(param) {
+			continue
+		}
+		present, err := strconv.ParseBool(query.Get(param))
+		if err != nil {
+			srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "Invalid %s: must be true or false", param)
+			return filter, false
+		}
+		if filter.has == nil {
+			filter.has = make(map[string]bool)
+		}
+		filter.has[field] = present
+	}
+
+	for _, value := range query["tag"] {
+		filter.tags.tags = append(filter.tags.tags, strings.Split(value, ",")...)
+	}
+	filter.tags.tags = normalizeTags(filter.tags.tags)
+	switch query.Get("tag_match") {
+	case "", "all":
+	case "any":
+		filter.tags.any = true
+	default:
+		srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "Invalid tag_match: must be all or any")
+		return filter, false
+	}
+	return filter, true
+}
+
+// Comprobar si un usuario pasa los filtros
+func (f userFilter) match(user User) bool {
+	if f.verified != nil && user.EmailVerified != *f.verified {
+		return false
+	}
+	for field, present := range f.has {
+		if optionalUserFields[field](user) != present {
+			return false
+		}
+	}
+	if !f.tags.match(user) {
+		return false
+	}
+	if f.hasModifiedSince {
+		return user.UpdatedAt.After(f.modifiedSince) || (user.DeletedAt != nil && user.DeletedAt.After(f.modifiedSince))
+	}
+	return user.DeletedAt == nil
+}
+
+// Número de usuarios de la lista que pasan los filtros
+func (f userFilter) count(list []User) int {
+	n := 0
+	for _, user := range list {
+		if f.match(user) {
+			n++
+		}
+	}
+	return n
+}
@@ -2,4 +2,8 @@ module microservicio-basico
 
 go 1.21
 
-require github.com/gorilla/mux v1.8.0
+require (
+	github.com/andybalholm/brotli v1.1.1
+	github.com/gorilla/mux v1.8.0
+	google.golang.org/protobuf v1.36.0
+)
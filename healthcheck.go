@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Veredictos de readiness: degraded indica que falla alguna dependencia no
+// crítica y el servicio sigue atendiendo
+const (
+	readyOK       = "ready"
+	readyDegraded = "degraded"
+	readyFailed   = "not_ready"
+)
+
+// Comprobación de una dependencia; debe respetar la cancelación de ctx
+type HealthCheck func(ctx context.Context) error
+
+type healthDependency struct {
+	name     string
+	critical bool
+	check    HealthCheck
+}
+
+// Estado de una dependencia en la respuesta de /health/ready
+type dependencyStatus struct {
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"`
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// Registro de las dependencias que /health/ready comprueba en paralelo
+type HealthChecker struct {
+	mu   sync.Mutex
+	deps []healthDependency
+}
+
+// Registrar una dependencia. Si una crítica falla el servicio no está listo;
+// si falla una que no lo es solo queda degradado.
+func (h *HealthChecker) Register(name string, critical bool, check HealthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.deps = append(h.deps, healthDependency{name: name, critical: critical, check: check})
+}
+
+// Ejecutar todas las comprobaciones en paralelo, cada una con el plazo
+// timeout, y devolver el veredicto y el estado de cada dependencia
+func (h *HealthChecker) Run(ctx context.Context, timeout time.Duration) (string, map[string]dependencyStatus) {
+	h.mu.Lock()
+	deps := append([]healthDependency(nil), h.deps...)
+	h.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	statuses := make([]dependencyStatus, len(deps))
+	var wg sync.WaitGroup
+	for i, dep := range deps {
+		wg.Add(1)
+		go func(i int, dep healthDependency) {
+			defer wg.Done()
+			statuses[i] = runCheck(ctx, dep)
+		}(i, dep)
+	}
+	wg.Wait()
+
+	verdict := readyOK
+	results := make(map[string]dependencyStatus, len(deps))
+	for i, dep := range deps {
+		results[dep.name] = statuses[i]
+		if statuses[i].Status == "ok" {
+			continue
+		}
+		if dep.critical {
+			verdict = readyFailed
+		} else if verdict == readyOK {
+			verdict = readyDegraded
+		}
+	}
+	return verdict, results
+}
+
+// Ejecutar una comprobación sin esperar más allá del plazo de ctx, aunque
+// la función no lo respete
+func runCheck(ctx context.Context, dep healthDependency) dependencyStatus {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- fmt.Errorf("check panicked: %v", rec)
+			}
+		}()
+		done <- dep.check(ctx)
+	}()
+
+	status := dependencyStatus{Status: "ok", Critical: dep.critical}
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	status.LatencyMS = time.Since(start).Milliseconds()
+	if errors.Is(err, context.DeadlineExceeded) {
+		status.Status = "timeout"
+		status.Error = "check did not complete in time"
+	} else if err != nil {
+		status.Status = "error"
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// Dependencias propias del servicio: el store debe responder (crítica) y
+// poder escribir (no crítica, las lecturas siguen funcionando sin ella); el
+// modo mantenimiento también degrada
+func (srv *server) defaultHealthChecker() *HealthChecker {
+	h := &HealthChecker{}
+	h.Register("store", true, func(ctx context.Context) error {
+		return srv.store.Ping(ctx)
+	})
+	h.Register("store_writes", false, func(ctx context.Context) error {
+		if srv.degraded.Load() {
+			return errors.New("data file is not writable")
+		}
+		if srv.storeBreaker != nil && srv.storeBreaker.remainingCooldown() > 0 {
+			return errCircuitOpen
+		}
+		return nil
+	})
+	h.Register("maintenance", false, srv.maintenanceCheck)
+	return h
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Acciones que se registran en el historial de un usuario
+const (
+	historyCreated  = "created"
+	historyUpdated  = "updated"
+	historyDeleted  = "deleted"
+	historyRestored = "restored"
+	historyPurged   = "purged"
+)
+
+// Quién hizo un cambio: el usuario autenticado, si lo hay, y su IP
+type historyActor struct {
+	UserID int    `json:"user_id,omitempty"`
+	IP     string `json:"ip"`
+}
+
+// Cambio de un campo; Old es null al crear y New al purgar o al quitar un
+// campo opcional, de modo que false o "" se distinguen de "sin valor"
+type fieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// Cambio registrado en el historial de un usuario
+type historyEntry struct {
+	Time      time.Time     `json:"time"`
+	Action    string        `json:"action"`
+	Actor     historyActor  `json:"actor"`
+	RequestID string        `json:"request_id,omitempty"`
+	Changes   []fieldChange `json:"changes"`
+}
+
+// Historial de cambios en memoria, por ID de usuario y en orden
+// cronológico; cada usuario conserva como mucho limit entradas
+// (HISTORY_LIMIT)
+type changeHistory struct {
+	mu      sync.Mutex
+	limit   int
+	entries map[int][]historyEntry
+}
+
+func (h *changeHistory) add(id int, entry historyEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	list := append(h.entries[id], entry)
+	if excess := len(list) - h.limit; excess > 0 {
+		list = slices.Delete(list, 0, excess)
+	}
+	h.entries[id] = list
+}
+
+// Copia del historial de un usuario
+func (h *changeHistory) get(id int) []historyEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Clone(h.entries[id])
+}
+
+// Registrar los cambios entre la lista actual y la que se va a aplicar.
+// Lo llama commitUsers, así que todas las mutaciones quedan registradas;
+// las recargas del archivo de datos no. Debe llamarse con usersMu bloqueado.
+func (srv *server) recordHistory(r *http.Request, current, next []User) {
+	if srv.config.HistoryLimit == 0 {
+		return
+	}
+
+	now := time.Now().UTC()
+	actor := historyActor{IP: srv.ClientIP(r)}
+	if caller, ok := callerFromContext(r.Context()); ok {
+		actor.UserID = caller.ID
+	}
+	requestID := requestIDFromContext(r.Context())
+	record := func(id int, action string, changes []fieldChange) {
+		srv.history.add(id, historyEntry{Time: now, Action: action, Actor: actor, RequestID: requestID, Changes: changes})
+	}
+
+	// Las mutaciones conservan las posiciones de la lista, así que casi
+	// siempre basta con comparar por índice
+	var byID map[int]int
+	previous := func(i int, id int) *User {
+		if i < len(current) && current[i].ID == id {
+			return &current[i]
+		}
+		if byID == nil {
+			byID = make(map[int]int, len(current))
+			for j, user := range current {
+				byID[user.ID] = j
+			}
+		}
+		if j, ok := byID[id]; ok {
+			return &current[j]
+		}
+		return nil
+	}
+
+	for i := range next {
+		user := &next[i]
+		old := previous(i, user.ID)
+		switch {
+		case old == nil:
+			record(user.ID, historyCreated, diffUsers(nil, user))
+		case !reflect.DeepEqual(*old, *user):
+			action := historyUpdated
+			if old.DeletedAt == nil && user.DeletedAt != nil {
+				action = historyDeleted
+			} else if old.DeletedAt != nil && user.DeletedAt == nil {
+				action = historyRestored
+			}
+			record(user.ID, action, diffUsers(old, user))
+		}
+	}
+
+	if len(next) < len(current) {
+		kept := make(map[int]bool, len(next))
+		for _, user := range next {
+			kept[user.ID] = true
+		}
+		for i := range current {
+			if !kept[current[i].ID] {
+				record(current[i].ID, historyPurged, diffUsers(&current[i], nil))
+			}
+		}
+	}
+}
+
+// Campos que cambian entre dos versiones de un usuario (nil si no existía),
+// con los nombres y valores de su JSON. updated_at no se incluye porque
+// cambia en cada modificación y ya está en la hora de la entrada.
+func diffUsers(old, updated *User) []fieldChange {
+	before, after := userFields(old), userFields(updated)
+	names := make([]string, 0, len(before)+len(after))
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := []fieldChange{}
+	for _, name := range names {
+		if name == "updated_at" || jsonValuesEqual(before[name], after[name]) {
+			continue
+		}
+		changes = append(changes, fieldChange{Field: name, Old: before[name], New: after[name]})
+	}
+	return changes
+}
+
+// Campos de un usuario tal como se serializan en JSON
+func userFields(user *User) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if user == nil {
+		return fields
+	}
+	data, err := json.Marshal(user)
+	if err != nil {
+		return fields
+	}
+	var value interface{}
+	if decodeJSONValue(data, &value) == nil {
+		if m, ok := value.(map[string]interface{}); ok {
+			fields = m
+		}
+	}
+	return fields
+}
+
+// Devolver el historial de cambios de un usuario, incluido uno eliminado,
+// en orden cronológico y paginable con ?page y ?per_page. El historial solo
+// vive en memoria: se pierde al reiniciar y no incluye los cambios que
+// llegan recargando el archivo de datos (ver userHistoryMethods).
+func (srv *server) userHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := srv.parseUserID(w, r)
+	if !ok {
+		return
+	}
+	p, perr := srv.parsePagination(r)
+	if perr != nil {
+		srv.writeAPIError(w, r, perr)
+		return
+	}
+
+	entries := srv.history.get(id)
+	if len(entries) == 0 {
+		srv.usersMu.RLock()
+		known := slices.ContainsFunc(srv.users, func(u User) bool { return u.ID == id })
+		srv.usersMu.RUnlock()
+		if !known {
+			srv.writeError(w, r, http.StatusNotFound, codeNotFound, "User not found")
+			return
+		}
+	}
+
+	srv.writeJSON(w, http.StatusOK, Response{
+		Status:  "success",
+		Message: "User history retrieved successfully",
+		Data:    applyPagination(w, r, p, entries),
+	})
+}
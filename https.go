@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Se redirige a HTTPS si el servicio tiene TLS propio o HTTPS_REDIRECT lo
+// pide (TLS terminado en un proxy)
+func (srv *server) httpsRedirectEnabled() bool {
+	return srv.config.TLSCertFile != "" || srv.config.HTTPSRedirect
+}
+
+// Valor de Strict-Transport-Security, o "" si HSTS está desactivado
+func (srv *server) hstsHeader() string {
+	if srv.config.HSTSMaxAge <= 0 {
+		return ""
+	}
+	value := fmt.Sprintf("max-age=%d", int64(srv.config.HSTSMaxAge.Seconds()))
+	if srv.config.HSTSIncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	return value
+}
+
+// Código de la redirección: 301 para GET y HEAD; el resto usa 308 para que
+// el cliente repita el mismo método con el mismo cuerpo
+func httpsRedirectStatus(r *http.Request) int {
+	if r.Method == "GET" || r.Method == "HEAD" {
+		return http.StatusMovedPermanently
+	}
+	return http.StatusPermanentRedirect
+}
+
+// Middleware que redirige a HTTPS las peticiones que un proxy de confianza
+// recibió en HTTP plano (X-Forwarded-Proto: http) y añade HSTS a las
+// seguras. Las peticiones directas sin esa cabecera no se redirigen, para no
+// romper los health checks que llegan sin pasar por el proxy.
+func (srv *server) httpsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := srv.requestBaseURL(r)
+		if strings.HasPrefix(base, "https://") {
+			if hsts := srv.hstsHeader(); hsts != "" {
+				w.Header().Set("Strict-Transport-Security", hsts)
+			}
+		} else if srv.httpsRedirectEnabled() && r.Header.Get("X-Forwarded-Proto") == "http" {
+			target := "https://" + strings.TrimPrefix(base, "http://") + r.URL.RequestURI()
+			http.Redirect(w, r, target, httpsRedirectStatus(r))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Servidor HTTP plano de HTTP_REDIRECT_PORT: redirige cualquier petición al
+// puerto HTTPS del servicio sin pasar por el router
+func newRedirectServer(cfg Config) *http.Server {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if cfg.Port != "443" {
+			host = net.JoinHostPort(host, cfg.Port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), httpsRedirectStatus(r))
+	})
+	return &http.Server{
+		Addr:         ":" + cfg.HTTPRedirectPort,
+		Handler:      handler,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Idiomas soportados para los mensajes de error; el primero es el de por defecto.
+// Los códigos de error (campo "code") no se traducen.
+const (
+	langEnglish = "en"
+	langSpanish = "es"
+)
+
+// Traducciones al español indexadas por el mensaje (o formato) en inglés
+var spanishMessages = map[string]string{
+	"A valid API key is required":                                                     "Se requiere una API key válida",
+	"A user with %s %q already exists":                                                "Ya existe un usuario con %s %q",
+	"Record %d: a user with %s %q already exists":                                     "Registro %d: ya existe un usuario con %s %q",
+	"Admin role required":                                                             "Se requiere el rol de administrador",
+	"At least one user is required":                                                   "Se requiere al menos un usuario",
+	"At most %d ids can be requested at once":                                         "Se pueden pedir como máximo %d ids a la vez",
+	"Could not read request body":                                                     "No se pudo leer el cuerpo de la petición",
+	"Data store is temporarily unavailable, changes were not saved":                   "El almacén de datos no está disponible temporalmente, los cambios no se guardaron",
+	"Data store is read-only, changes were not saved":                                 "El almacén de datos es de solo lectura, los cambios no se guardaron",
+	"Data file was modified externally and is being reloaded, changes were not saved": "El archivo de datos se modificó externamente y se está recargando, los cambios no se guardaron",
+	"Duplicate JSON key %q":                                                           "Clave JSON duplicada %q",
+	"Field %q is immutable and cannot be modified":                                    "El campo %q es inmutable y no se puede modificar",
+	"JSON nesting exceeds the maximum depth of %d":                                    "El anidamiento JSON supera la profundidad máxima de %d",
+	"Injected failure (chaos testing)":                                                "Fallo inyectado (pruebas de caos)",
+	"Internal server error":                                                           "Error interno del servidor",
+	"Invalid JSON format":                                                             "Formato JSON inválido",
+	"Expected a JSON object, got %s":                                                  "Se esperaba un objeto JSON y se recibió %s",
+	"Expected a JSON array, got %s":                                                   "Se esperaba un array JSON y se recibió %s",
+	"an object":                                                                       "un objeto",
+	"an array":                                                                        "un array",
+	"a string":                                                                        "una cadena",
+	"a number":                                                                        "un número",
+	"a boolean":                                                                       "un booleano",
+	"To create several users, send the array to POST /api/users/bulk":                 "Para crear varios usuarios, envía el array a POST /api/users/bulk",
+	"To create a single user, send the object to POST /api/users":                     "Para crear un solo usuario, envía el objeto a POST /api/users",
+	"To update a single user, send the object to PUT /api/users/{id}":                 "Para actualizar un solo usuario, envía el objeto a PUT /api/users/{id}",
+	"Invalid format: must be csv or ndjson":                                           "Formato inválido: debe ser csv o ndjson",
+	"Invalid before: must be an RFC 3339 timestamp or a date (YYYY-MM-DD)":            "before inválido: debe ser una fecha RFC 3339 o un día (AAAA-MM-DD)",
+	"Invalid cursor: must be a non-negative user ID":                                  "Cursor inválido: debe ser un ID de usuario no negativo",
+	"Invalid page: must be a positive integer":                                        "page inválido: debe ser un entero positivo",
+	"Invalid per_page: must be between 1 and %d":                                      "per_page inválido: debe estar entre 1 y %d",
+	"Invalid mode: must be atomic or best_effort":                                     "Modo inválido: debe ser atomic o best_effort",
+	"Record %d: field %q is immutable and cannot be modified":                         "Registro %d: el campo %q es inmutable y no se puede modificar",
+	"Record %d: user not found":                                                       "Registro %d: usuario no encontrado",
+	"Invalid modified_since: must be an RFC 3339 timestamp":                           "modified_since inválido: debe ser una fecha RFC 3339",
+	"Invalid fields: unknown field %q":                                                "fields inválido: campo desconocido %q",
+	"Invalid tag_match: must be all or any":                                           "tag_match inválido: debe ser all o any",
+	"At least one tag is required":                                                    "Se requiere al menos una etiqueta",
+	"Invalid %s: must be true or false":                                               "%s inválido: debe ser true o false",
+	"Invalid verified: must be true or false":                                         "verified inválido: debe ser true o false",
+	"Invalid user ID %q":                                                              "ID de usuario inválido %q",
+	"Invalid user ID: must be a positive integer":                                     "ID de usuario inválido: debe ser un entero positivo",
+	"Only admins can assign the admin role":                                           "Solo los administradores pueden asignar el rol de administrador",
+	"Only admins can change roles":                                                    "Solo los administradores pueden cambiar roles",
+	"Rate limit exceeded":                                                             "Límite de peticiones excedido",
+	"Record %d: %s":                                                                   "Registro %d: %s",
+	"Record %d: a valid user ID is required":                                          "Registro %d: se requiere un ID de usuario válido",
+	"Record %d: duplicate user ID %d":                                                 "Registro %d: ID de usuario %d duplicado",
+	"Request body must contain a single JSON object":                                  "El cuerpo de la petición debe contener un único objeto JSON",
+	"Request body must not exceed %d bytes":                                           "El cuerpo de la petición no debe superar %d bytes",
+	"Request body is not valid gzip":                                                  "El cuerpo de la petición no es gzip válido",
+	"Unsupported Content-Encoding %q: only gzip is accepted":                          "Content-Encoding %q no soportado: solo se acepta gzip",
+	"Request body must be valid UTF-8":                                                "El cuerpo de la petición debe ser UTF-8 válido",
+	"Request did not complete within %v":                                              "La petición no terminó en %v",
+	"Request body was sent too slowly":                                                "El cuerpo de la petición se envió demasiado lento",
+	"Too many filter parameters: at most %d are allowed":                              "Demasiados parámetros de filtro: se admiten como máximo %d",
+	"X-Request-Nonce is required and must be at most 128 characters":                  "X-Request-Nonce es obligatorio y debe tener como máximo 128 caracteres",
+	"X-Request-Timestamp must be a Unix timestamp in seconds":                         "X-Request-Timestamp debe ser una marca de tiempo Unix en segundos",
+	"X-Request-Timestamp is outside the allowed clock skew of %v":                     "X-Request-Timestamp está fuera de la desviación de reloj permitida de %v",
+	"Request nonce has already been used":                                             "El nonce de la petición ya se ha usado",
+	"Service in maintenance mode":                                                     "Servicio en modo mantenimiento",
+	"The enabled field is required":                                                   "El campo enabled es obligatorio",
+	"The email parameter is required":                                                 "El parámetro email es obligatorio",
+	"The ids parameter is required":                                                   "El parámetro ids es obligatorio",
+	"Unknown query parameters: %s":                                                    "Parámetros de consulta desconocidos: %s",
+	"User not found":                                                                  "Usuario no encontrado",
+
+	"Name and email are required": "El nombre y el email son obligatorios",
+	"Name is required":            "El nombre es obligatorio",
+	"Email is required":           "El email es obligatorio",
+	nameTooLongMessage:            fmt.Sprintf("El nombre debe tener como máximo %d caracteres", maxNameLength),
+	emailTooLongMessage:           fmt.Sprintf("El email debe tener como máximo %d caracteres", maxEmailLength),
+	"Invalid email format":        "Formato de email inválido",
+	tooManyTagsMessage:            fmt.Sprintf("Se admiten como máximo %d etiquetas", maxTags),
+	invalidTagMessage:             fmt.Sprintf("Las etiquetas deben tener de 1 a %d letras minúsculas, dígitos, '-', '_', '.' o ':' sin espacios", maxTagLength),
+	"Email domain is not allowed": "El dominio del email no está permitido",
+	"Role must be one of: " + strings.Join(allowedRoles, ", "): "El rol debe ser uno de: " + strings.Join(allowedRoles, ", "),
+
+	"Operation %d: unsupported path %q":             "Operación %d: ruta no soportada %q",
+	"Operation %d: %q requires a value":             "Operación %d: %q requiere un valor",
+	"Operation %d: cannot replace missing field %q": "Operación %d: no se puede reemplazar el campo inexistente %q",
+	"Operation %d: cannot remove missing field %q":  "Operación %d: no se puede eliminar el campo inexistente %q",
+	"Operation %d: test failed for %q":              "Operación %d: la comprobación falló para %q",
+	"Operation %d: unsupported op %q":               "Operación %d: operación no soportada %q",
+	"Patched document is not a valid user":          "El documento parcheado no es un usuario válido",
+}
+
+// Elegir el idioma de la respuesta según Accept-Language (RFC 9110 §12.5.4)
+func requestLanguage(r *http.Request) string {
+	best, bestQ := langEnglish, 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if primary != langEnglish && primary != langSpanish {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > bestQ {
+			best, bestQ = primary, q
+		}
+	}
+	return best
+}
+
+// Argumento de localize que es a su vez un mensaje del catálogo (p. ej. el
+// de validateUser dentro de "Record %d: %s") y se traduce con él. El resto
+// de argumentos, que pueden venir del cliente, nunca se traducen.
+type messageKey string
+
+// Traducir un mensaje de error al idioma de la petición, junto con los
+// argumentos marcados como messageKey. No modifica args.
+func localize(r *http.Request, format string, args ...interface{}) string {
+	spanish := requestLanguage(r) == langSpanish
+	if spanish {
+		if translated, ok := spanishMessages[format]; ok {
+			format = translated
+		}
+	}
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		key, ok := arg.(messageKey)
+		if !ok {
+			values[i] = arg
+			continue
+		}
+		values[i] = string(key)
+		if translated, found := spanishMessages[string(key)]; spanish && found {
+			values[i] = translated
+		}
+	}
+	return fmt.Sprintf(format, values...)
+}
@@ -0,0 +1,23 @@
+package main
+
+// Tipo de los identificadores de usuario
+type ID = int
+
+// Estrategia de asignación de IDs a los usuarios nuevos. Next se llama con
+// usersMu bloqueado para escritura; un ID devuelto no se vuelve a entregar
+// aunque la creación falle después.
+type IDGenerator interface {
+	Next() ID
+}
+
+// IDs enteros consecutivos a partir de *next, el nextID del servidor, que se
+// persiste con los datos
+type sequentialIDs struct {
+	next *int
+}
+
+func (s sequentialIDs) Next() ID {
+	id := *s.next
+	*s.next++
+	return id
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Email repetido entre usuarios no eliminados y los IDs que lo comparten
+type duplicateEmail struct {
+	Email string `json:"email"`
+	IDs   []int  `json:"ids"`
+}
+
+// Buscar emails repetidos (sin distinguir mayúsculas) entre los usuarios no
+// eliminados; los datos antiguos pueden ser anteriores a la unicidad
+func findDuplicateEmails(list []User) []duplicateEmail {
+	byEmail := make(map[string][]int)
+	var order []string
+	for _, user := range list {
+		if user.DeletedAt != nil {
+			continue
+		}
+		key := strings.ToLower(user.Email)
+		if _, seen := byEmail[key]; !seen {
+			order = append(order, key)
+		}
+		byEmail[key] = append(byEmail[key], user.ID)
+	}
+
+	duplicates := []duplicateEmail{}
+	for _, email := range order {
+		if ids := byEmail[email]; len(ids) > 1 {
+			sort.Ints(ids)
+			duplicates = append(duplicates, duplicateEmail{Email: email, IDs: ids})
+		}
+	}
+	return duplicates
+}
+
+// Resumen de integridad para /health, que no requiere autenticación: solo
+// cuántos emails se repiten; los emails y los IDs afectados están en
+// /admin/integrity
+func integritySummary(duplicates []duplicateEmail) map[string]interface{} {
+	status := "ok"
+	if len(duplicates) > 0 {
+		status = "duplicate_emails"
+	}
+	return map[string]interface{}{
+		"status":           status,
+		"duplicate_emails": len(duplicates),
+	}
+}
+
+// Informe completo de integridad, con los emails repetidos (solo admins)
+func (srv *server) integrityHandler(w http.ResponseWriter, r *http.Request) {
+	srv.usersMu.RLock()
+	duplicates := srv.duplicateEmails
+	srv.usersMu.RUnlock()
+
+	srv.writeJSON(w, http.StatusOK, Response{
+		Status:  "success",
+		Message: "Data integrity report",
+		Data:    map[string]interface{}{"duplicate_emails": duplicates},
+	})
+}
+
+// Analizar los datos cargados e informar de los emails duplicados; con
+// FAIL_ON_DUPLICATE_EMAILS el arranque se detiene
+func (srv *server) checkDataIntegrity() {
+	srv.duplicateEmails = findDuplicateEmails(srv.users)
+	for _, dup := range srv.duplicateEmails {
+		log.Printf("WARN: email %s is shared by users %v", dup.Email, dup.IDs)
+	}
+	if len(srv.duplicateEmails) > 0 && srv.config.FailOnDuplicateEmails {
+		log.Fatalf("Found %d duplicate emails in %s; fix the data or unset FAIL_ON_DUPLICATE_EMAILS", len(srv.duplicateEmails), srv.config.DataFile)
+	}
+}
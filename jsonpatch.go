@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Operación de un documento JSON Patch (RFC 6902)
+type patchOperation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// Aplicar las operaciones sobre la representación JSON del usuario
+func applyJSONPatch(user User, ops []patchOperation) (User, *apiError) {
+	// Números como json.Number: un valor como 9007199254740993 no debe
+	// redondearse al comparar (test) ni al escribirse en el usuario
+	raw, _ := json.Marshal(user)
+	var decoded interface{}
+	decodeJSONValue(raw, &decoded)
+	doc := decoded.(map[string]interface{})
+
+	for i, op := range ops {
+		field, ok := patchField(op.Path)
+		if !ok {
+			return user, newAPIError(http.StatusUnprocessableEntity, codeValidationFailed, "Operation %d: unsupported path %q", i, op.Path)
+		}
+		if op.Op != "test" && (slices.Contains(immutableUserFields, field) || field == "updated_at" || field == "email_verified") {
+			return user, newAPIError(http.StatusBadRequest, codeImmutableField, "Field %q is immutable and cannot be modified", field)
+		}
+
+		var value interface{}
+		if op.Op == "add" || op.Op == "replace" || op.Op == "test" {
+			if op.Value == nil {
+				return user, newAPIError(http.StatusBadRequest, codeBadRequest, "Operation %d: %q requires a value", i, op.Op)
+			}
+			decodeJSONValue(*op.Value, &value)
+		}
+
+		_, exists := doc[field]
+		switch op.Op {
+		case "add":
+			doc[field] = value
+		case "replace":
+			if !exists {
+				return user, newAPIError(http.StatusUnprocessableEntity, codeValidationFailed, "Operation %d: cannot replace missing field %q", i, field)
+			}
+			doc[field] = value
+		case "remove":
+			if !exists {
+				return user, newAPIError(http.StatusUnprocessableEntity, codeValidationFailed, "Operation %d: cannot remove missing field %q", i, field)
+			}
+			delete(doc, field)
+		case "test":
+			if !jsonValuesEqual(doc[field], value) {
+				return user, newAPIError(http.StatusConflict, codeTestFailed, "Operation %d: test failed for %q", i, op.Path)
+			}
+		default:
+			return user, newAPIError(http.StatusBadRequest, codeBadRequest, "Operation %d: unsupported op %q", i, op.Op)
+		}
+	}
+
+	raw, _ = json.Marshal(doc)
+	var patched User
+	if err := json.Unmarshal(raw, &patched); err != nil {
+		return user, newAPIError(http.StatusUnprocessableEntity, codeValidationFailed, "Patched document is not a valid user")
+	}
+	return patched, nil
+}
+
+// Convertir un JSON Pointer de un solo nivel en el nombre del campo
+func patchField(path string) (string, bool) {
+	field, ok := strings.CutPrefix(path, "/")
+	if !ok || field == "" || strings.Contains(field, "/") {
+		return "", false
+	}
+	field = strings.ReplaceAll(strings.ReplaceAll(field, "~1", "/"), "~0", "~")
+	return field, true
+}
+
+// Aplicar un JSON Patch (application/json-patch+json) a un usuario
+func (srv *server) jsonPatchUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := srv.parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var ops []patchOperation
+	if !srv.decodeJSONBody(w, r, &ops) {
+		return
+	}
+
+	srv.usersMu.Lock()
+	defer srv.usersMu.Unlock()
+
+	i := indexOfUser(srv.users, id)
+	if i < 0 {
+		srv.writeError(w, r, http.StatusNotFound, codeNotFound, "User not found")
+		return
+	}
+	user := srv.users[i]
+
+	patched, perr := applyJSONPatch(user, ops)
+	if perr != nil {
+		srv.writeAPIError(w, r, perr)
+		return
+	}
+
+	srv.normalizeUser(&patched)
+	if errs := srv.validateUserFields(patched); len(errs) > 0 {
+		srv.writeValidationErrors(w, r, errs)
+		return
+	}
+	if patched.Role != user.Role && !srv.isAdmin(r) {
+		srv.writeError(w, r, http.StatusForbidden, codeForbidden, "Only admins can change roles")
+		return
+	}
+
+	if field := srv.findUniqueConflict(srv.users, patched, user.ID); field != "" {
+		srv.writeUniqueConflict(w, r, field, patched)
+		return
+	}
+
+	keepVerification(&patched, user)
+	patched.UpdatedAt = time.Now().UTC()
+	next := slices.Clone(srv.users)
+	next[i] = patched
+	if !srv.commitUsers(w, r, next) {
+		return
+	}
+
+	srv.writeUserResult(w, r, http.StatusOK, "User updated successfully", patched)
+}
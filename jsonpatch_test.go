@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// 2^53 + 1: el primer entero que float64 no puede representar
+const beyondFloat64 = 9007199254740993
+
+func TestDecodeJSONValueKeepsLargeIntegers(t *testing.T) {
+	var value interface{}
+	if err := decodeJSONValue([]byte(`{"n":9007199254740993}`), &value); err != nil {
+		t.Fatal(err)
+	}
+	out, _ := json.Marshal(value)
+	if string(out) != `{"n":9007199254740993}` {
+		t.Errorf("round trip gave %s", out)
+	}
+	if jsonValuesEqual(json.Number("9007199254740993"), json.Number("9007199254740992")) {
+		t.Error("2^53+1 and 2^53 compared equal, as they would through float64")
+	}
+	if !jsonValuesEqual(json.Number("1"), json.Number("1.0")) {
+		t.Error("1 and 1.0 must compare equal (RFC 6902 §4.6)")
+	}
+}
+
+func TestJSONPatchTestKeepsLargeIntegerPrecision(t *testing.T) {
+	user := User{ID: beyondFloat64, Name: "Ana Ruiz", Email: "ana@example.com"}
+
+	// float64 redondea 2^53+1 a 2^53, así que esto pasaría sin json.Number
+	ops := []patchOperation{{Op: "test", Path: "/id", Value: rawJSON("9007199254740992")}}
+	if _, err := applyJSONPatch(user, ops); err == nil || err.status != http.StatusConflict {
+		t.Errorf("test against 2^53 = %v, want a 409 test failure", err)
+	}
+
+	ops = []patchOperation{
+		{Op: "test", Path: "/id", Value: rawJSON("9007199254740993")},
+		{Op: "replace", Path: "/name", Value: rawJSON(`"Ana María Ruiz"`)},
+	}
+	patched, err := applyJSONPatch(user, ops)
+	if err != nil {
+		t.Fatalf("test against the exact ID failed: %v", err)
+	}
+	if patched.ID != beyondFloat64 {
+		t.Errorf("patched ID %d, want %d", patched.ID, beyondFloat64)
+	}
+}
+
+// Valor de una operación de JSON Patch
+func rawJSON(s string) *json.RawMessage {
+	raw := json.RawMessage(s)
+	return &raw
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Tipo de medio con el que el cliente pide enlaces HAL en la respuesta
+const halMediaType = "application/hal+json"
+
+// Enlace de navegación hacia una acción sobre el recurso
+type link struct {
+	Href   string `json:"href"`
+	Method string `json:"method,omitempty"`
+}
+
+// Usuario con sus enlaces _links
+type userWithLinks struct {
+	User
+	Links map[string]link `json:"_links"`
+}
+
+// El cliente pide enlaces con ?links=true o con Accept: application/hal+json
+func wantsLinks(r *http.Request) bool {
+	if enabled, err := strconv.ParseBool(r.URL.Query().Get("links")); err == nil {
+		return enabled
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(accept); err == nil && mediaType == halMediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// URL base pública del servicio. X-Forwarded-Proto, X-Forwarded-Host y
+// X-Forwarded-Prefix solo se respetan si la conexión viene de un proxy de
+// confianza, igual que X-Forwarded-For en ClientIP.
+func (srv *server) requestBaseURL(r *http.Request) string {
+	scheme, host, prefix := "http", r.Host, ""
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+	if srv.isTrustedProxy(peer) {
+		if v := r.Header.Get("X-Forwarded-Proto"); v == "http" || v == "https" {
+			scheme = v
+		}
+		if v := r.Header.Get("X-Forwarded-Host"); v != "" {
+			host = strings.TrimSpace(strings.Split(v, ",")[0])
+		}
+		prefix = strings.TrimSuffix(r.Header.Get("X-Forwarded-Prefix"), "/")
+	}
+	return scheme + "://" + host + prefix
+}
+
+// Datos de un usuario para la respuesta, con _links si el cliente los pidió
+func (srv *server) userData(r *http.Request, user User) interface{} {
+	if !wantsLinks(r) {
+		return user
+	}
+
+	href := srv.requestBaseURL(r) + "/api/users/" + strconv.Itoa(user.ID)
+	return userWithLinks{
+		User: user,
+		Links: map[string]link{
+			"self":   {Href: href, Method: "GET"},
+			"update": {Href: href, Method: "PUT"},
+			"delete": {Href: href, Method: "DELETE"},
+		},
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Entrada del log de accesos que se envía por /admin/logs/stream
+type accessLogEntry struct {
+	Time       time.Time `json:"time"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Status     int       `json:"status"`
+	DurationMS float64   `json:"duration_ms"`
+	ClientIP   string    `json:"client_ip"`
+}
+
+// Cliente del stream. ch hace de buffer circular: si se llena se descarta
+// la entrada más antigua y se cuenta en dropped.
+type logSubscriber struct {
+	ch      chan accessLogEntry
+	dropped atomic.Int64
+}
+
+// Reparto de las entradas del log a los clientes conectados sin bloquear
+// nunca la petición que las genera
+type logBroadcaster struct {
+	mu          sync.Mutex
+	subscribers map[*logSubscriber]struct{}
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+func (b *logBroadcaster) subscribe(size int) *logSubscriber {
+	sub := &logSubscriber{ch: make(chan accessLogEntry, size)}
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub
+}
+
+func (b *logBroadcaster) unsubscribe(sub *logSubscriber) {
+	b.mu.Lock()
+	delete(b.subscribers, sub)
+	b.mu.Unlock()
+}
+
+// Enviar una entrada a todos los clientes
+func (b *logBroadcaster) publish(entry accessLogEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subscribers {
+		for {
+			select {
+			case sub.ch <- entry:
+			default:
+				// Lleno: descartar la más antigua y volver a intentarlo
+				select {
+				case <-sub.ch:
+					sub.dropped.Add(1)
+				default:
+				}
+				continue
+			}
+			break
+		}
+	}
+}
+
+// Terminar los streams abiertos para que no retengan el apagado
+func (b *logBroadcaster) close() {
+	b.closeOnce.Do(func() { close(b.done) })
+}
+
+// Intervalo de los comentarios SSE que mantienen viva la conexión
+const logStreamKeepAlive = 15 * time.Second
+
+// Plazo de escritura de cada evento; se renueva antes de cada uno para que
+// un cliente que deja de leer no bloquee su goroutine indefinidamente
+const logStreamWriteTimeout = 10 * time.Second
+
+// Enviar en tiempo real las entradas del log de accesos como Server-Sent
+// Events: "access" por cada petición y "dropped" con las que se perdieron
+// porque el cliente no daba abasto
+func (srv *server) logStreamHandler(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	// El stream dura más que WRITE_TIMEOUT, así que el plazo se fija por evento
+	rc.SetWriteDeadline(time.Now().Add(logStreamWriteTimeout))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	sub := srv.accessLog.subscribe(srv.config.LogStreamBuffer)
+	defer srv.accessLog.unsubscribe(sub)
+
+	keepAlive := time.NewTicker(logStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		var event string
+		select {
+		case entry := <-sub.ch:
+			if n := sub.dropped.Swap(0); n > 0 {
+				event = fmt.Sprintf("event: dropped\ndata: {\"count\":%d}\n\n", n)
+			}
+			data, _ := json.Marshal(entry)
+			event += fmt.Sprintf("event: access\ndata: %s\n\n", data)
+		case <-keepAlive.C:
+			event = ": keep-alive\n\n"
+		case <-r.Context().Done():
+			return
+		case <-srv.accessLog.done:
+			return
+		}
+		rc.SetWriteDeadline(time.Now().Add(logStreamWriteTimeout))
+		if _, err := io.WriteString(w, event); err != nil || rc.Flush() != nil {
+			return
+		}
+	}
+}
+
+// ResponseWriter que guarda el estado de la respuesta para el log
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if sw.status == 0 {
+		sw.status = status
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	return sw.ResponseWriter.Write(b)
+}
+
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
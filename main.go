@@ -1,11 +1,23 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"math"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -16,267 +28,739 @@ type User struct {
 	ID    int    `json:"id"`
 	Name  string `json:"name"`
 	Email string `json:"email"`
+	Role  string `json:"role,omitempty"`
+	// Etiquetas en minúsculas y sin repetir (ver tags.go)
+	Tags []string `json:"tags,omitempty"`
+	// Solo se marca a través de POST /api/users/{id}/verify-email
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	// Marca de borrado lógico; los usuarios eliminados se conservan como
+	// tombstones para la sincronización incremental
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 // Respuesta estándar de la API
 type Response struct {
 	Status  string      `json:"status"`
 	Message string      `json:"message"`
+	Code    string      `json:"code,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
+	// Errores por campo del formato de validación structured
+	Errors []fieldError `json:"errors,omitempty"`
 }
 
-// Base de datos en memoria (en producción usarías una DB real)
-var users = []User{
-	{ID: 1, Name: "Juan Pérez", Email: "juan@example.com"},
-	{ID: 2, Name: "María García", Email: "maria@example.com"},
+// Completar los campos que controla el servidor al sustituir un usuario
+// existente con los datos de un cuerpo (PUT y upsert)
+func replaceUser(updated *User, current User) {
+	updated.ID = current.ID
+	updated.CreatedAt = current.CreatedAt
+	updated.UpdatedAt = time.Now().UTC()
+	updated.DeletedAt = nil
+	keepVerification(updated, current)
 }
 
-var nextID = 3
+// Índice del usuario no eliminado con ese email (sin distinguir mayúsculas),
+// ignorando el usuario exceptID, o -1
+func indexOfEmail(list []User, email string, exceptID int) int {
+	return slices.IndexFunc(list, func(u User) bool {
+		return u.ID != exceptID && u.DeletedAt == nil && strings.EqualFold(u.Email, email)
+	})
+}
+
+// Conservar la verificación del email en una actualización; los clientes no
+// pueden cambiarla y se pierde si cambia el email
+func keepVerification(updated *User, current User) {
+	updated.EmailVerified = current.EmailVerified && updated.Email == current.Email
+}
+
+// Índice del usuario no eliminado con ese ID, o -1
+func indexOfUser(list []User, id int) int {
+	return slices.IndexFunc(list, func(u User) bool { return u.ID == id && u.DeletedAt == nil })
+}
+
+// Buffer con su encoder para serializar una respuesta antes de enviarla
+type jsonBuffer struct {
+	bytes.Buffer
+	enc *json.Encoder
+}
+
+// Buffers reutilizables de writeJSON, para no reservar un encoder y su
+// memoria en cada respuesta
+var jsonBuffers = sync.Pool{New: func() interface{} {
+	buf := new(jsonBuffer)
+	buf.enc = json.NewEncoder(&buf.Buffer)
+	return buf
+}}
+
+// Devolver un buffer al pool salvo que haya crecido por encima de
+// JSON_POOL_MAX_BUFFER, para no retener la memoria de respuestas grandes
+func (srv *server) releaseJSONBuffer(buf *jsonBuffer) {
+	if buf.Cap() <= srv.config.JSONPoolMaxBuffer {
+		buf.Reset()
+		jsonBuffers.Put(buf)
+	}
+}
+
+// Escribir una respuesta JSON con el código de estado indicado. Se
+// serializa entera antes de escribir nada, así que un error de
+// serialización se responde como 500 en lugar de enviar JSON a medias.
+func (srv *server) writeJSON(w http.ResponseWriter, status int, response Response) {
+	// Todo 429 y 503 indica cuándo reintentar; quien conozca un plazo mejor
+	// (rate limit, circuit breaker) lo fija antes con setRetryAfter
+	if (status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable) && w.Header().Get("Retry-After") == "" {
+		setRetryAfter(w, srv.config.RetryAfter)
+	}
+
+	buf := jsonBuffers.Get().(*jsonBuffer)
+	defer srv.releaseJSONBuffer(buf)
+	buf.Reset()
+	if err := buf.enc.Encode(response); err != nil {
+		log.Printf("ERROR: could not encode JSON response: %v", err)
+		status = http.StatusInternalServerError
+		buf.Reset()
+		buf.WriteString(`{"status":"error","message":"Internal server error","code":"internal_error"}` + "\n")
+	}
+
+	w.Header().Set("Content-Type", jsonMediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// Fijar Retry-After en segundos enteros, redondeando hacia arriba y como
+// mínimo 1
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	seconds := max(int(math.Ceil(d.Seconds())), 1)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+}
+
+// Escribir una respuesta de error estándar con su código (ver errors.go).
+// El mensaje se traduce al idioma del cliente (ver i18n.go).
+func (srv *server) writeError(w http.ResponseWriter, r *http.Request, status int, code, format string, args ...interface{}) {
+	w.Header().Set("Content-Language", requestLanguage(r))
+	w.Header().Add("Vary", "Accept-Language")
+	srv.writeJSON(w, status, Response{
+		Status:  "error",
+		Message: localize(r, format, args...),
+		Code:    code,
+	})
+}
 
 // Middleware para logging
-func loggingMiddleware(next http.Handler) http.Handler {
+func (srv *server) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		log.Printf("Started %s %s", r.Method, r.URL.Path)
-		
-		next.ServeHTTP(w, r)
-		
-		log.Printf("Completed %s %s in %v", r.Method, r.URL.Path, time.Since(start))
+		log.Printf("Started %s %s from %s", r.Method, r.URL.Path, srv.ClientIP(r))
+
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		elapsed := time.Since(start)
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		srv.accessLog.publish(accessLogEntry{
+			Time:       start.UTC(),
+			RequestID:  requestIDFromContext(r.Context()),
+			Method:     r.Method,
+			Path:       redactText(r.URL.Path, srv.config.LogRedact, srv.config.APIKeys),
+			Status:     sw.status,
+			DurationMS: float64(elapsed.Microseconds()) / 1000,
+			ClientIP:   srv.ClientIP(r),
+		})
+		if elapsed > srv.config.SlowRequestThreshold {
+			log.Printf("WARN: Slow request %s %s took %v (threshold %v)", r.Method, r.URL.Path, elapsed, srv.config.SlowRequestThreshold)
+			return
+		}
+		log.Printf("Completed %s %s in %v", r.Method, r.URL.Path, elapsed)
 	})
 }
 
+// Registrar un mensaje solo con LOG_LEVEL=debug
+func (srv *server) debugf(format string, args ...interface{}) {
+	if srv.config.LogLevel == "debug" {
+		log.Printf("DEBUG: "+format, args...)
+	}
+}
+
+// Comprobar si el cliente ya se desconectó para dejar de trabajar en una
+// respuesta que nadie va a leer
+func (srv *server) clientGone(r *http.Request) bool {
+	if err := r.Context().Err(); err != nil {
+		srv.debugf("Client went away during %s %s: %v", r.Method, r.URL.Path, err)
+		return true
+	}
+	return false
+}
+
 // Middleware para CORS
-func corsMiddleware(next http.Handler) http.Handler {
+func (srv *server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-		
-		if r.Method == "OPTIONS" {
+		w.Header().Set("Access-Control-Allow-Origin", srv.config.CORSAllowOrigin)
+		w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Encoding, X-API-Key, X-Request-ID, If-None-Match, Prefer, X-Request-Nonce, X-Request-Timestamp, X-Feature-Flags")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Total-Count, ETag, Link, Location, Preference-Applied, X-Deduplicated, X-Feature-Flags-Applied")
+
+		// Solo las peticiones preflight se responden aquí; el resto de OPTIONS
+		// llega al handler de descubrimiento
+		if r.Method == "OPTIONS" && r.Header.Get("Origin") != "" && r.Header.Get("Access-Control-Request-Method") != "" {
 			w.WriteHeader(http.StatusOK)
 			return
 		}
-		
+
 		next.ServeHTTP(w, r)
 	})
 }
 
 // Health check endpoint
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	response := Response{
+func (srv *server) healthHandler(w http.ResponseWriter, r *http.Request) {
+	status := "healthy"
+	message := "Service is healthy"
+	if srv.degraded.Load() {
+		status = "degraded"
+		message = "Service is degraded: data file is not writable"
+	}
+
+	srv.usersMu.RLock()
+	duplicates := srv.duplicateEmails
+	srv.usersMu.RUnlock()
+
+	srv.writeJSON(w, http.StatusOK, Response{
 		Status:  "success",
-		Message: "Service is healthy",
+		Message: message,
 		Data: map[string]interface{}{
+			"status":    status,
 			"timestamp": time.Now().Format(time.RFC3339),
 			"uptime":    time.Since(startTime).String(),
+			"build":     buildInfo(),
+			"integrity": integritySummary(duplicates),
 		},
+	})
+}
+
+var startTime = time.Now().UTC()
+
+// Readiness: ejecuta las comprobaciones registradas en readiness (ver
+// healthcheck.go). Responde 503 si falla alguna dependencia crítica.
+func (srv *server) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	if srv.shuttingDown.Load() {
+		setRetryAfter(w, srv.config.ShutdownTimeout)
+		srv.writeJSON(w, http.StatusServiceUnavailable, Response{
+			Status:  "error",
+			Message: "Service is not ready: shutting down",
+		})
+		return
+	}
+
+	verdict, dependencies := srv.readiness.Run(r.Context(), srv.config.HealthCheckTimeout)
+	data := map[string]interface{}{"status": verdict, "dependencies": dependencies}
+	switch verdict {
+	case readyFailed:
+		srv.writeJSON(w, http.StatusServiceUnavailable, Response{
+			Status:  "error",
+			Message: "Service is not ready: a critical dependency check failed",
+			Data:    data,
+		})
+	case readyDegraded:
+		srv.writeJSON(w, http.StatusOK, Response{
+			Status:  "success",
+			Message: "Service is ready but degraded: a non-critical dependency check failed",
+			Data:    data,
+		})
+	default:
+		srv.writeJSON(w, http.StatusOK, Response{
+			Status:  "success",
+			Message: "Service is ready",
+			Data:    data,
+		})
 	}
-	json.NewEncoder(w).Encode(response)
 }
 
-var startTime = time.Now()
+// Obtener todos los usuarios. Con ?modified_since=<RFC 3339> solo se
+// devuelven los creados, modificados o eliminados después de esa fecha,
+// incluidos los tombstones de los eliminados.
+func (srv *server) getUsersHandler(w http.ResponseWriter, r *http.Request) {
+	filter, ok := srv.parseUserFilter(w, r)
+	if !ok {
+		return
+	}
+	page, perr := srv.parsePagination(r)
+	if perr != nil {
+		srv.writeAPIError(w, r, perr)
+		return
+	}
 
-// Obtener todos los usuarios
-func getUsersHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	response := Response{
+	srv.usersMu.RLock()
+	version := srv.dataVersion.Load()
+	result := []User{}
+	for _, user := range srv.users {
+		if filter.match(user) {
+			result = append(result, user)
+		}
+	}
+	srv.usersMu.RUnlock()
+
+	mediaType := userListMediaType(r)
+	w.Header().Add("Vary", "Accept")
+	if srv.clientGone(r) || checkNotModified(w, r, collectionETag(version, mediaType), time.Time{}) {
+		return
+	}
+
+	result = applyPagination(w, r, page, result)
+	if mediaType == protobufMediaType {
+		srv.writeProtobuf(w, r, http.StatusOK, userListProto(result))
+		return
+	}
+	srv.writeJSON(w, http.StatusOK, Response{
 		Status:  "success",
 		Message: "Users retrieved successfully",
-		Data:    users,
+		Data:    result,
+	})
+}
+
+// Contar los usuarios del listado sin devolverlos; HEAD /api/users solo
+// responde con X-Total-Count (y Link si se pide una página) y admite los
+// mismos filtros y parámetros de paginación que GET
+func (srv *server) headUsersHandler(w http.ResponseWriter, r *http.Request) {
+	filter, ok := srv.parseUserFilter(w, r)
+	if !ok {
+		return
+	}
+	page, perr := srv.parsePagination(r)
+	if perr != nil {
+		srv.writeAPIError(w, r, perr)
+		return
+	}
+
+	srv.usersMu.RLock()
+	version := srv.dataVersion.Load()
+	count := filter.count(srv.users)
+	srv.usersMu.RUnlock()
+
+	setPaginationHeaders(w, r, page, count)
+	w.Header().Add("Vary", "Accept")
+	if checkNotModified(w, r, collectionETag(version, userListMediaType(r)), time.Time{}) {
+		return
 	}
-	json.NewEncoder(w).Encode(response)
+	w.WriteHeader(http.StatusOK)
 }
 
 // Obtener un usuario por ID
-func getUserHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
-	
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		response := Response{
-			Status:  "error",
-			Message: "Invalid user ID",
-		}
-		json.NewEncoder(w).Encode(response)
+func (srv *server) getUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := srv.parseUserID(w, r)
+	if !ok {
 		return
 	}
-	
-	for _, user := range users {
-		if user.ID == id {
-			response := Response{
-				Status:  "success",
-				Message: "User found",
-				Data:    user,
-			}
-			json.NewEncoder(w).Encode(response)
-			return
-		}
+
+	srv.usersMu.RLock()
+	defer srv.usersMu.RUnlock()
+
+	i := indexOfUser(srv.users, id)
+	if i < 0 {
+		srv.writeError(w, r, http.StatusNotFound, codeNotFound, "User not found")
+		return
 	}
-	
-	w.WriteHeader(http.StatusNotFound)
-	response := Response{
-		Status:  "error",
-		Message: "User not found",
+	user := srv.users[i]
+	mediaType := userItemMediaType(r)
+	w.Header().Add("Vary", "Accept")
+	if checkNotModified(w, r, userETag(user, mediaType), user.UpdatedAt) {
+		return
 	}
-	json.NewEncoder(w).Encode(response)
+	switch mediaType {
+	case vcardMediaType:
+		writeVCard(w, http.StatusOK, user)
+		return
+	case protobufMediaType:
+		srv.writeProtobuf(w, r, http.StatusOK, userProto(user))
+		return
+	}
+	srv.writeJSON(w, http.StatusOK, Response{
+		Status:  "success",
+		Message: "User found",
+		Data:    srv.userData(r, user),
+	})
+}
+
+// Leer el ID de la ruta; solo se aceptan enteros positivos, así que un ID
+// mal formado responde 400 y nunca se confunde con un 404
+func (srv *server) parseUserID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		srv.writeError(w, r, http.StatusBadRequest, codeInvalidID, "Invalid user ID: must be a positive integer")
+		return 0, false
+	}
+	return id, true
 }
 
 // Crear un nuevo usuario
-func createUserHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	
+func (srv *server) createUserHandler(w http.ResponseWriter, r *http.Request) {
 	var newUser User
-	err := json.NewDecoder(r.Body).Decode(&newUser)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		response := Response{
-			Status:  "error",
-			Message: "Invalid JSON format",
-		}
-		json.NewEncoder(w).Encode(response)
+	if !srv.decodeJSONBody(w, r, &newUser) {
 		return
 	}
-	
+
 	// Validación básica
-	if newUser.Name == "" || newUser.Email == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		response := Response{
-			Status:  "error",
-			Message: "Name and email are required",
+	srv.normalizeUser(&newUser)
+	if errs := srv.validateUserFields(newUser); len(errs) > 0 {
+		srv.writeValidationErrors(w, r, errs)
+		return
+	}
+
+	if newUser.Role == roleAdmin && !srv.isAdmin(r) {
+		srv.writeError(w, r, http.StatusForbidden, codeForbidden, "Only admins can assign the admin role")
+		return
+	}
+
+	srv.usersMu.Lock()
+	defer srv.usersMu.Unlock()
+
+	// Con ?upsert=true un email ya registrado actualiza ese usuario (200) en
+	// lugar de responder 409
+	if i := indexOfEmail(srv.users, newUser.Email, 0); i >= 0 {
+		if upsert, _ := strconv.ParseBool(r.URL.Query().Get("upsert")); !upsert {
+			srv.writeUniqueConflict(w, r, "email", newUser)
+			return
 		}
-		json.NewEncoder(w).Encode(response)
+		existing := srv.users[i]
+		if newUser.Role != existing.Role && !srv.isAdmin(r) {
+			srv.writeError(w, r, http.StatusForbidden, codeForbidden, "Only admins can change roles")
+			return
+		}
+		if field := srv.findUniqueConflict(srv.users, newUser, existing.ID); field != "" {
+			srv.writeUniqueConflict(w, r, field, newUser)
+			return
+		}
+		replaceUser(&newUser, existing)
+		next := slices.Clone(srv.users)
+		next[i] = newUser
+		if !srv.commitUsers(w, r, next) {
+			return
+		}
+		srv.writeUserResult(w, r, http.StatusOK, "User updated successfully", newUser)
 		return
 	}
-	
-	// Asignar ID y agregar a la lista
-	newUser.ID = nextID
-	nextID++
-	users = append(users, newUser)
-	
-	w.WriteHeader(http.StatusCreated)
-	response := Response{
-		Status:  "success",
-		Message: "User created successfully",
-		Data:    newUser,
+
+	if field := srv.findUniqueConflict(srv.users, newUser, 0); field != "" {
+		srv.writeUniqueConflict(w, r, field, newUser)
+		return
+	}
+
+	// Asignar ID y valores por defecto y agregar a la lista
+	srv.applyUserDefaults(&newUser)
+	newUser.ID = srv.idGenerator.Next()
+	newUser.CreatedAt = time.Now().UTC()
+	newUser.UpdatedAt = newUser.CreatedAt
+	newUser.DeletedAt = nil
+	newUser.EmailVerified = false
+	if !srv.commitUsers(w, r, append(slices.Clone(srv.users), newUser)) {
+		return
+	}
+
+	srv.writeUserResult(w, r, http.StatusCreated, "User created successfully", newUser)
+}
+
+// Validar un usuario; devuelve el mensaje flat del primer error o "" (ver
+// validateUserFields)
+func (srv *server) validateUser(user User) string {
+	return flatValidationMessage(srv.validateUserFields(user))
+}
+
+// Mensajes de validación que dependen de los límites de schema.go
+var (
+	nameTooLongMessage  = fmt.Sprintf("Name must be at most %d characters", maxNameLength)
+	emailTooLongMessage = fmt.Sprintf("Email must be at most %d characters", maxEmailLength)
+)
+
+// Completar los campos opcionales que llegan vacíos con los valores por
+// defecto de config; solo se aplica a usuarios nuevos, nunca a los que
+// se actualizan, y no pisa los valores indicados. DEFAULT_ROLE se valida al
+// arrancar, así que el resultado sigue siendo válido. User no tiene país ni
+// dirección, así que no hay DEFAULT_COUNTRY; su valor por defecto iría aquí
+// junto al campo.
+func (srv *server) applyUserDefaults(user *User) {
+	if user.Role == "" {
+		user.Role = srv.config.DefaultRole
+	}
+}
+
+// Normalizar los datos de un usuario antes de validarlos
+func (srv *server) normalizeUser(user *User) {
+	srv.applyFieldNormalization(user)
+	user.Tags = normalizeTags(user.Tags)
+
+	// Completar el dominio por defecto si el email no tiene ninguno
+	if srv.config.DefaultEmailDomain != "" && user.Email != "" && !strings.ContainsAny(user.Email, "@ ") {
+		user.Email += "@" + srv.config.DefaultEmailDomain
 	}
-	json.NewEncoder(w).Encode(response)
 }
 
 // Actualizar un usuario
-func updateUserHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
-	
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		response := Response{
-			Status:  "error",
-			Message: "Invalid user ID",
-		}
-		json.NewEncoder(w).Encode(response)
+func (srv *server) updateUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := srv.parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	body, ok := srv.readJSONBody(w, r)
+	if !ok {
 		return
 	}
-	
 	var updatedUser User
-	err = json.NewDecoder(r.Body).Decode(&updatedUser)
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		response := Response{
-			Status:  "error",
-			Message: "Invalid JSON format",
-		}
-		json.NewEncoder(w).Encode(response)
-		return
-	}
-	
-	for i, user := range users {
-		if user.ID == id {
-			updatedUser.ID = id
-			users[i] = updatedUser
-			response := Response{
-				Status:  "success",
-				Message: "User updated successfully",
-				Data:    updatedUser,
-			}
-			json.NewEncoder(w).Encode(response)
-			return
-		}
+	var fields map[string]json.RawMessage
+	if !srv.unmarshalJSONBody(w, r, body, &updatedUser) || !srv.unmarshalJSONBody(w, r, body, &fields) {
+		return
 	}
-	
-	w.WriteHeader(http.StatusNotFound)
-	response := Response{
-		Status:  "error",
-		Message: "User not found",
+
+	srv.normalizeUser(&updatedUser)
+	if errs := srv.validateUserFields(updatedUser); len(errs) > 0 {
+		srv.writeValidationErrors(w, r, errs)
+		return
+	}
+
+	srv.usersMu.Lock()
+	defer srv.usersMu.Unlock()
+
+	i := indexOfUser(srv.users, id)
+	if i < 0 {
+		srv.writeError(w, r, http.StatusNotFound, codeNotFound, "User not found")
+		return
+	}
+	user := srv.users[i]
+
+	if field := checkImmutableFields(fields, user); field != "" {
+		srv.writeError(w, r, http.StatusBadRequest, codeImmutableField, "Field %q is immutable and cannot be modified", field)
+		return
+	}
+	if updatedUser.Role != user.Role && !srv.isAdmin(r) {
+		srv.writeError(w, r, http.StatusForbidden, codeForbidden, "Only admins can change roles")
+		return
 	}
-	json.NewEncoder(w).Encode(response)
+	if field := srv.findUniqueConflict(srv.users, updatedUser, id); field != "" {
+		srv.writeUniqueConflict(w, r, field, updatedUser)
+		return
+	}
+	replaceUser(&updatedUser, user)
+	next := slices.Clone(srv.users)
+	next[i] = updatedUser
+	if !srv.commitUsers(w, r, next) {
+		return
+	}
+	srv.writeUserResult(w, r, http.StatusOK, "User updated successfully", updatedUser)
 }
 
-// Eliminar un usuario
-func deleteUserHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	vars := mux.Vars(r)
-	id, err := strconv.Atoi(vars["id"])
-	
-	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		response := Response{
-			Status:  "error",
-			Message: "Invalid user ID",
-		}
-		json.NewEncoder(w).Encode(response)
+// Marcar como verificado el email de un usuario
+func (srv *server) verifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := srv.parseUserID(w, r)
+	if !ok {
 		return
 	}
-	
-	for i, user := range users {
-		if user.ID == id {
-			users = append(users[:i], users[i+1:]...)
-			response := Response{
-				Status:  "success",
-				Message: "User deleted successfully",
-			}
-			json.NewEncoder(w).Encode(response)
+
+	srv.usersMu.Lock()
+	defer srv.usersMu.Unlock()
+
+	i := indexOfUser(srv.users, id)
+	if i < 0 {
+		srv.writeError(w, r, http.StatusNotFound, codeNotFound, "User not found")
+		return
+	}
+
+	next := slices.Clone(srv.users)
+	if !next[i].EmailVerified {
+		next[i].EmailVerified = true
+		next[i].UpdatedAt = time.Now().UTC()
+		if !srv.commitUsers(w, r, next) {
 			return
 		}
 	}
-	
-	w.WriteHeader(http.StatusNotFound)
-	response := Response{
-		Status:  "error",
-		Message: "User not found",
+	srv.writeJSON(w, http.StatusOK, Response{
+		Status:  "success",
+		Message: "Email verified",
+		Data:    srv.userData(r, next[i]),
+	})
+}
+
+// Eliminar un usuario. El borrado es lógico: se conserva un tombstone con
+// deleted_at para que ?modified_since propague la eliminación.
+func (srv *server) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := srv.parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	srv.usersMu.Lock()
+	defer srv.usersMu.Unlock()
+
+	i := indexOfUser(srv.users, id)
+	if i < 0 {
+		srv.writeError(w, r, http.StatusNotFound, codeNotFound, "User not found")
+		return
 	}
-	json.NewEncoder(w).Encode(response)
+
+	now := time.Now().UTC()
+	next := slices.Clone(srv.users)
+	next[i].UpdatedAt = now
+	next[i].DeletedAt = &now
+	if !srv.commitUsers(w, r, next) {
+		return
+	}
+	srv.writeJSON(w, http.StatusOK, Response{
+		Status:  "success",
+		Message: "User deleted successfully",
+	})
 }
 
+// Código de salida cuando el puerto configurado ya está ocupado
+const exitPortInUse = 3
+
 func main() {
-	// Crear router
-	r := mux.NewRouter()
-	
-	// Aplicar middlewares
-	r.Use(loggingMiddleware)
-	r.Use(corsMiddleware)
-	
-	// Definir rutas
-	r.HandleFunc("/health", healthHandler).Methods("GET")
-	r.HandleFunc("/api/users", getUsersHandler).Methods("GET")
-	r.HandleFunc("/api/users/{id}", getUserHandler).Methods("GET")
-	r.HandleFunc("/api/users", createUserHandler).Methods("POST")
-	r.HandleFunc("/api/users/{id}", updateUserHandler).Methods("PUT")
-	r.HandleFunc("/api/users/{id}", deleteUserHandler).Methods("DELETE")
-	
-	// Configurar puerto
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-	
+	// Cargar configuración
+	cfg, err := LoadConfig()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	if cfg.LogRedact != redactOff {
+		log.SetOutput(redactingWriter{out: os.Stderr, mode: cfg.LogRedact, apiKeys: cfg.APIKeys})
+	}
+
+	// Cargar datos persistidos (opcional)
+	var backend Store = memoryStore{}
+	if cfg.DataFile != "" {
+		backend = &fileStore{path: cfg.DataFile, watch: cfg.WatchInterval > 0}
+	}
+	srv := newServer(backend, cfg)
+	if fs, ok := backend.(*fileStore); ok {
+		// Sin el directorio la primera escritura fallaría con un error poco claro
+		created, err := fs.ensureDir()
+		if err != nil {
+			log.Fatalf("Failed to create data directory %s for DATA_FILE: %v", filepath.Dir(cfg.DataFile), err)
+		}
+		if created {
+			log.Printf("Created data directory %s", filepath.Dir(cfg.DataFile))
+		}
+		if err := srv.loadUsers(); err != nil {
+			log.Fatalf("Failed to load data file %s: %v", cfg.DataFile, err)
+		}
+		if err := fs.checkWritable(); err != nil {
+			srv.markDegraded(err)
+		}
+	}
+
+	srv.checkDataIntegrity()
+	if err := srv.selfCheck(); err != nil {
+		log.Fatalf("Self-check failed:\n%v", err)
+	}
+
+	port := srv.config.Port
+	// Abrir el puerto antes de arrancar para dar un error claro si está ocupado
+	ln, err := net.Listen("tcp", ":"+port)
+	if errors.Is(err, syscall.EADDRINUSE) {
+		log.Printf("ERROR: port %s is already in use, probably by another instance of this service. Stop it or set PORT to a free port (e.g. PORT=8081).", port)
+		os.Exit(exitPortInUse)
+	}
+	if err != nil {
+		log.Fatalf("Failed to listen on port %s: %v", port, err)
+	}
+
+	scheme := "http"
+	if srv.config.TLSCertFile != "" {
+		scheme = "https"
+	}
 	log.Printf("Server starting on port %s", port)
-	log.Printf("Health check available at: http://localhost:%s/health", port)
-	log.Printf("API endpoints available at: http://localhost:%s/api/users", port)
-	
+	log.Printf("Health check available at: %s://localhost:%s/health", scheme, port)
+	log.Printf("API endpoints available at: %s://localhost:%s/api/users", scheme, port)
+
 	// Iniciar servidor
-	log.Fatal(http.ListenAndServe(":"+port, r))
-}
\ No newline at end of file
+	go func() {
+		var err error
+		if srv.config.TLSCertFile != "" {
+			srv.http.TLSConfig = srv.config.tlsConfig()
+			err = srv.http.ServeTLS(ln, srv.config.TLSCertFile, srv.config.TLSKeyFile)
+		} else {
+			err = srv.http.Serve(ln)
+		}
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	// Puerto HTTP plano que solo redirige a HTTPS
+	var redirectServer *http.Server
+	if srv.config.HTTPRedirectPort != "" {
+		redirectServer = newRedirectServer(srv.config)
+		log.Printf("Redirecting plain HTTP on port %s to HTTPS", srv.config.HTTPRedirectPort)
+		go func() {
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Failed to serve HTTP redirects on port %s: %v", srv.config.HTTPRedirectPort, err)
+			}
+		}()
+	}
+
+	// Escritura periódica en modo async
+	stopFlusher := make(chan struct{})
+	flusherDone := make(chan struct{})
+	if srv.config.DataFile != "" && srv.config.PersistMode == "async" {
+		go srv.runFlusher(srv.config.FlushInterval, stopFlusher, flusherDone)
+	} else {
+		close(flusherDone)
+	}
+
+	// Recarga del archivo de datos si se edita fuera del servicio
+	stopWatcher := make(chan struct{})
+	watcherDone := make(chan struct{})
+	if backend, ok := srv.store.(*fileStore); ok && backend.watch {
+		go srv.runFileWatcher(backend, srv.config.WatchInterval, stopWatcher, watcherDone)
+	} else {
+		close(watcherDone)
+	}
+
+	// Apagado ordenado al recibir SIGINT o SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	srv.shuttingDown.Store(true)
+	// Las respuestas en curso salen con Connection: close y las conexiones
+	// keep-alive inactivas se liberan ya, sin esperar a IDLE_TIMEOUT.
+	// Comprobación manual: abrir una conexión keep-alive inactiva (p. ej.
+	// curl --next) y una petición lenta, enviar SIGTERM y ver que la lenta
+	// termina y el apagado acaba antes de SHUTDOWN_TIMEOUT.
+	srv.http.SetKeepAlivesEnabled(false)
+	log.Printf("Shutting down server with %d active requests...", srv.activeRequests.Load())
+	drainStart := time.Now()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), srv.config.ShutdownTimeout)
+	defer cancel()
+
+	// Informar del progreso del drenado mientras dura
+	drained := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				log.Printf("Draining: %d active requests after %v", srv.activeRequests.Load(), time.Since(drainStart).Round(time.Millisecond))
+			case <-drained:
+				return
+			}
+		}
+	}()
+
+	if redirectServer != nil {
+		redirectServer.Shutdown(shutdownCtx)
+	}
+	err = srv.http.Shutdown(shutdownCtx)
+	close(drained)
+	if err != nil {
+		log.Printf("Graceful shutdown did not complete after %v: %v (%d requests still active)", time.Since(drainStart).Round(time.Millisecond), err, srv.activeRequests.Load())
+	} else {
+		log.Printf("Drained connections in %v", time.Since(drainStart).Round(time.Millisecond))
+	}
+
+	// Último flush para no perder cambios pendientes
+	close(stopWatcher)
+	<-watcherDone
+	close(stopFlusher)
+	<-flusherDone
+	log.Printf("Server stopped (%d active requests)", srv.activeRequests.Load())
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Retry-After debe ser un número entero de segundos, nunca una fecha
+func retryAfterSeconds(t *testing.T, resp *http.Response) int {
+	t.Helper()
+	header := resp.Header.Get("Retry-After")
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 1 {
+		t.Fatalf("status %d: Retry-After %q is not a positive number of seconds", resp.StatusCode, header)
+	}
+	return seconds
+}
+
+func TestRetryAfterOnRateLimit(t *testing.T) {
+	_, ts := newTestServer(t, memoryStore{}, func(cfg *Config) {
+		cfg.RateLimitRPS = 0.5
+		cfg.RateLimitBurst = 1
+	})
+
+	doRequest(t, ts, "GET", "/api/users", "")
+	resp, data := doRequest(t, ts, "GET", "/api/users", "")
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("second request: status %d, want 429: %s", resp.StatusCode, data)
+	}
+	// Un token cada 2 s
+	if got := retryAfterSeconds(t, resp); got != 2 {
+		t.Errorf("Retry-After %d, want 2", got)
+	}
+}
+
+func TestRetryAfterOnServiceUnavailable(t *testing.T) {
+	_, ts := newTestServer(t, memoryStore{}, func(cfg *Config) {
+		cfg.MaintenanceMode = true
+		cfg.RetryAfter = 1500 * time.Millisecond
+	})
+
+	resp, data := doRequest(t, ts, "POST", "/api/users", `{"name":"Ana Ruiz","email":"ana@example.com"}`)
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("POST in maintenance: status %d, want 503: %s", resp.StatusCode, data)
+	}
+	// Las fracciones se redondean hacia arriba
+	if got := retryAfterSeconds(t, resp); got != 2 {
+		t.Errorf("Retry-After %d, want 2", got)
+	}
+}
+
+func TestShutdownReleasesIdleKeepAlives(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.ChaosLatencyMS = 300
+	srv := newServer(memoryStore{}, cfg)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.http.Serve(ln)
+	addr := ln.Addr().String()
+
+	// Conexión keep-alive que queda inactiva tras una petición
+	idle, reader := dialTestServer(t, addr)
+	fmt.Fprint(idle, "GET /health HTTP/1.1\r\nHost: test\r\n\r\n")
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	// Petición lenta en curso durante el apagado
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	slow := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/api/users")
+		if err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		slow <- result{resp, err}
+	}()
+	for deadline := time.Now().Add(2 * time.Second); srv.activeRequests.Load() == 0; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("slow request never started")
+		}
+	}
+
+	// Mismo orden que main; IDLE_TIMEOUT (120 s) es mucho mayor que el plazo
+	srv.http.SetKeepAlivesEnabled(false)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := srv.http.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown did not complete within the timeout: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("shutdown took %v with an idle keep-alive", elapsed)
+	}
+
+	got := <-slow
+	if got.err != nil {
+		t.Fatalf("in-flight request failed during shutdown: %v", got.err)
+	}
+	if got.resp.StatusCode != http.StatusOK || !got.resp.Close {
+		t.Errorf("in-flight request: status %d, close %v; want 200 with Connection: close", got.resp.StatusCode, got.resp.Close)
+	}
+	if _, err := reader.ReadByte(); err == nil {
+		t.Error("idle keep-alive connection is still open after shutdown")
+	}
+}
+
+// ResponseWriter que descarta el cuerpo, para medir solo writeJSON
+type discardResponseWriter struct{ header http.Header }
+
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
+
+// Antes: writeJSON tal como era sin el pool, codificando directamente sobre w
+func writeJSONDirect(w http.ResponseWriter, status int, response Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Comparar writeJSON con y sin el pool de buffers sobre un listado de 200
+// usuarios: go test -bench WriteJSON -benchmem
+func BenchmarkWriteJSON(b *testing.B) {
+	users := make([]User, 200)
+	for i := range users {
+		users[i] = User{ID: i + 1, Name: fmt.Sprintf("User %d", i), Email: fmt.Sprintf("user%d@example.com", i), Role: roleMember}
+	}
+	response := Response{Status: "success", Data: users}
+
+	cfg := defaultConfig()
+	pooled := newServer(memoryStore{}, cfg)
+	cfg.JSONPoolMaxBuffer = 0
+	unpooled := newServer(memoryStore{}, cfg)
+
+	run := func(write func(http.ResponseWriter, int, Response)) func(*testing.B) {
+		return func(b *testing.B) {
+			b.ReportAllocs()
+			w := &discardResponseWriter{header: make(http.Header)}
+			for i := 0; i < b.N; i++ {
+				write(w, http.StatusOK, response)
+			}
+		}
+	}
+	b.Run("before", run(writeJSONDirect))
+	b.Run("pooled", run(pooled.writeJSON))
+	b.Run("pool_disabled", run(unpooled.writeJSON))
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// Rutas que aceptan métodos de escritura en mantenimiento: el propio
+// interruptor y la validación, que no modifica nada
+var maintenanceExempt = map[string]bool{
+	"/admin/maintenance":  true,
+	"/api/users/validate": true,
+}
+
+// Middleware que responde 503 a las peticiones que modifican datos mientras
+// dura el mantenimiento
+func (srv *server) maintenanceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		template, _ := routeTemplate(r)
+		if srv.maintenance.Load() && isMutating(r.Method) && !maintenanceExempt[template] {
+			srv.writeError(w, r, http.StatusServiceUnavailable, codeMaintenance, "Service in maintenance mode")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Comprobación de readiness: el mantenimiento degrada el servicio sin
+// sacarlo del balanceador, porque las lecturas siguen disponibles
+func (srv *server) maintenanceCheck(ctx context.Context) error {
+	if srv.maintenance.Load() {
+		return errors.New("service in maintenance mode, writes are rejected")
+	}
+	return nil
+}
+
+// Consultar (GET) o cambiar (PUT {"enabled": true|false}) el modo
+// mantenimiento
+func (srv *server) maintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "PUT" {
+		var body struct {
+			Enabled *bool `json:"enabled"`
+		}
+		if !srv.decodeJSONBody(w, r, &body) {
+			return
+		}
+		if body.Enabled == nil {
+			srv.writeError(w, r, http.StatusUnprocessableEntity, codeValidationFailed, "The enabled field is required")
+			return
+		}
+		if srv.maintenance.Swap(*body.Enabled) != *body.Enabled {
+			state := "disabled"
+			if *body.Enabled {
+				state = "enabled"
+			}
+			log.Printf("Maintenance mode %s by %s", state, srv.callerLabel(r))
+		}
+	}
+
+	srv.writeJSON(w, http.StatusOK, Response{
+		Status:  "success",
+		Message: "Maintenance mode status",
+		Data:    map[string]interface{}{"enabled": srv.maintenance.Load()},
+	})
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// Resolver /api/users/me al usuario autenticado y delegar en el handler de
+// /api/users/{id}. Sin API key (o sin autenticación configurada) no hay a
+// quién resolver y se responde 401. La API key de un usuario eliminado ya no
+// autentica (401); si se elimina durante la petición, el handler responde 404.
+func (srv *server) meHandler(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := callerFromContext(r.Context())
+		if !ok {
+			srv.writeError(w, r, http.StatusUnauthorized, codeUnauthorized, "A valid API key is required")
+			return
+		}
+		next(w, mux.SetURLVars(r, map[string]string{"id": strconv.Itoa(caller.ID)}))
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// Middleware que cuenta las peticiones activas
+func (srv *server) activeRequestsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srv.activeRequests.Add(1)
+		defer srv.activeRequests.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Middleware que convierte un panic en un 500. Con DEBUG_ERRORS la
+// respuesta incluye el mensaje y la traza; si no, solo se registran en el log
+func (srv *server) recoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// El servidor usa este valor para abortar la respuesta a propósito
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			stack := debug.Stack()
+			log.Printf("PANIC: %s %s: %v\n%s", r.Method, r.URL.Path, rec, stack)
+
+			if !srv.config.DebugErrors {
+				srv.writeError(w, r, http.StatusInternalServerError, codeInternalError, "Internal server error")
+				return
+			}
+			w.Header().Set("Content-Language", requestLanguage(r))
+			srv.writeJSON(w, http.StatusInternalServerError, Response{
+				Status:  "error",
+				Message: localize(r, "Internal server error"),
+				Code:    codeInternalError,
+				Data: map[string]interface{}{
+					"panic": fmt.Sprint(rec),
+					"stack": string(stack),
+				},
+			})
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Middleware que rechaza cuerpos de petición que no sean UTF-8 válido
+func (srv *server) utf8BodyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil || r.Method == "GET" || r.Method == "HEAD" || r.Method == "OPTIONS" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if streamsBody(r) {
+			r.Body = &utf8Reader{body: r.Body}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			srv.writeBodyReadError(w, r, err)
+			return
+		}
+
+		if !utf8.Valid(body) {
+			srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "Request body must be valid UTF-8")
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Middleware que añade las cabeceras configuradas para la ruta (ROUTE_HEADERS);
+// se aplican antes del handler, que puede sobrescribirlas
+func (srv *server) routeHeadersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if template, ok := routeTemplate(r); ok {
+			for _, key := range []string{template, r.Method + " " + template} {
+				for name, value := range srv.config.RouteHeaders[key] {
+					w.Header().Set(name, value)
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Plantilla de mux de la ruta que atiende la petición, p. ej. /api/users/{id}
+func routeTemplate(r *http.Request) (string, bool) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "", false
+	}
+	template, err := route.GetPathTemplate()
+	return template, err == nil
+}
+
+// Plazo de la petición: ROUTE_TIMEOUTS por método y ruta, por ruta o, si
+// no hay, REQUEST_TIMEOUT
+func (srv *server) requestTimeout(r *http.Request) time.Duration {
+	if template, ok := routeTemplate(r); ok {
+		for _, key := range []string{r.Method + " " + template, template} {
+			if timeout, ok := srv.config.RouteTimeouts[key]; ok {
+				return timeout
+			}
+		}
+	}
+	return srv.config.RequestTimeout
+}
+
+// Margen de escritura tras el plazo para poder enviar el 503
+const timeoutWriteGrace = time.Second
+
+// Middleware que limita la duración de cada petición. Cancela el contexto
+// al vencer el plazo, para que los handlers dejen de trabajar, y ajusta el
+// límite de escritura de la conexión al mismo plazo (más timeoutWriteGrace).
+// Si el handler no llegó a responder se envía un 503.
+func (srv *server) timeoutMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout := srv.requestTimeout(r)
+		if timeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + timeoutWriteGrace))
+
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		if sw.status == 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			srv.writeError(w, r, http.StatusServiceUnavailable, codeRequestTimeout, "Request did not complete within %v", timeout)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Tipo de medio de la representación por defecto
+const jsonMediaType = "application/json"
+
+// Representaciones de GET /api/users/{id} y de GET /api/users, en orden de
+// preferencia a igualdad de q; la primera es la de por defecto
+var (
+	userItemMediaTypes = []string{jsonMediaType, vcardMediaType, protobufMediaType}
+	userListMediaTypes = []string{jsonMediaType, protobufMediaType}
+)
+
+// Sufijo de las ETags de cada representación, para que una caché no
+// confunda el JSON con el protobuf o el vCard del mismo recurso
+var representationTags = map[string]string{
+	jsonMediaType:     "json",
+	vcardMediaType:    "vcard",
+	protobufMediaType: "pb",
+}
+
+// Elegir entre offers el tipo de medio que prefiere Accept (RFC 9110
+// §12.5.1). Cada oferta toma la q del rango más específico que la cubre
+// (tipo/subtipo, tipo/* o */*) y gana la de mayor q; q=0 la excluye. Sin
+// Accept, o si el cliente no acepta ninguna, se responde con la primera.
+func negotiateMediaType(accept string, offers []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	best, bestQ := offers[0], 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(part)
+			if err != nil {
+				continue
+			}
+			s := mediaRangeSpecificity(mediaType, offer)
+			if s <= specificity {
+				continue
+			}
+			value := 1.0
+			if raw, ok := params["q"]; ok {
+				parsed, err := strconv.ParseFloat(raw, 64)
+				if err != nil {
+					continue
+				}
+				value = parsed
+			}
+			q, specificity = value, s
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// Especificidad con la que el rango de Accept cubre el tipo de medio: 2 si
+// coincide exactamente, 1 para tipo/*, 0 para */* y -1 si no lo cubre
+func mediaRangeSpecificity(mediaRange, mediaType string) int {
+	switch {
+	case mediaRange == mediaType:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*")):
+		return 1
+	}
+	return -1
+}
+
+// Representación de un usuario: ?format=vcard manda sobre Accept
+func userItemMediaType(r *http.Request) string {
+	if r.URL.Query().Get("format") == "vcard" {
+		return vcardMediaType
+	}
+	return negotiateMediaType(r.Header.Get("Accept"), userItemMediaTypes)
+}
+
+// Representación del listado según Accept
+func userListMediaType(r *http.Request) string {
+	return negotiateMediaType(r.Header.Get("Accept"), userListMediaTypes)
+}
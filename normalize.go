@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+)
+
+// Pasos de normalización que se pueden aplicar a un campo de texto
+var normalizeSteps = map[string]func(string) string{
+	// Quitar los espacios de alrededor, conservando los interiores
+	"trim": strings.TrimSpace,
+	// Reducir cada racha de espacios interiores a uno solo
+	"collapse_spaces": func(s string) string { return strings.Join(strings.Fields(s), " ") },
+	"lowercase":       strings.ToLower,
+}
+
+// Campos de User que admiten normalización
+var normalizableFields = map[string]func(*User) *string{
+	"name":  func(u *User) *string { return &u.Name },
+	"email": func(u *User) *string { return &u.Email },
+	"role":  func(u *User) *string { return &u.Role },
+}
+
+// Aplicar a un usuario los pasos configurados para cada campo, en orden.
+// Se llama desde normalizeUser, así que crear, actualizar, parchear y las
+// operaciones en bloque normalizan igual.
+func (srv *server) applyFieldNormalization(user *User) {
+	for field, steps := range srv.config.FieldNormalization {
+		value := normalizableFields[field](user)
+		for _, step := range steps {
+			*value = normalizeSteps[step](*value)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Descripción de un método soportado por un recurso
+type MethodInfo struct {
+	Method      string `json:"method"`
+	Description string `json:"description"`
+}
+
+// Métodos soportados por /api/users
+var usersCollectionMethods = []MethodInfo{
+	{Method: "GET", Description: "List all users; filter with ?verified, ?modified_since and ?has_role=true|false (users with or without a role); with ?page and ?per_page the list is paginated and a Link header points to the other pages"},
+	{Method: "HEAD", Description: "Return only the number of users matching the filters in X-Total-Count"},
+	{Method: "POST", Description: "Create a user from a JSON body with name and email; with ?upsert=true an existing email updates that user instead"},
+	{Method: "OPTIONS", Description: "Describe the methods supported by this resource"},
+}
+
+// Métodos soportados por /api/users/{id}
+var userItemMethods = []MethodInfo{
+	{Method: "GET", Description: "Retrieve the user with the given ID"},
+	{Method: "PUT", Description: "Replace the user with the given ID from a JSON body"},
+	{Method: "PATCH", Description: "Update only the fields present in the JSON body, or apply an RFC 6902 JSON Patch with Content-Type application/json-patch+json"},
+	{Method: "DELETE", Description: "Delete the user with the given ID"},
+	{Method: "OPTIONS", Description: "Describe the methods supported by this resource"},
+}
+
+// Métodos soportados por /api/users/{id}/history
+var userHistoryMethods = []MethodInfo{
+	{Method: "GET", Description: "List the recorded changes of the user with the given ID in chronological order (admin only), paginated with ?page and ?per_page. History is kept in memory only: it starts empty after a restart, keeps at most HISTORY_LIMIT entries per user and does not include changes loaded from an externally edited data file"},
+	{Method: "OPTIONS", Description: "Describe the methods supported by this resource"},
+}
+
+// Handler de OPTIONS que anuncia los métodos de un recurso y enlaza al
+// JSON Schema con sus reglas de validación
+func (srv *server) optionsHandler(methods []MethodInfo) http.HandlerFunc {
+	allow := make([]string, len(methods))
+	for i, m := range methods {
+		allow[i] = m.Method
+	}
+	allowHeader := strings.Join(allow, ", ")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", allowHeader)
+		w.Header().Set("Link", `</api/schema/user>; rel="describedby"`)
+		srv.writeJSON(w, http.StatusOK, Response{
+			Status:  "success",
+			Message: "Supported methods",
+			Data:    methods,
+		})
+	}
+}
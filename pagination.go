@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Tamaño de página por defecto y máximo de ?per_page
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// Página pedida con ?page y ?per_page, igual en todos los endpoints de
+// listado (listado, HEAD y exportación). Sin ninguno de los dos la lista se
+// devuelve completa, como antes de paginar.
+type Pagination struct {
+	enabled bool
+	number  int
+	size    int
+	// URL pública del servidor, para los enlaces de Link
+	baseURL string
+}
+
+// Leer ?page y ?per_page. Un valor que no es un entero es un error; uno
+// fuera de rango se recorta (page a 1 como mínimo, per_page entre 1 y
+// maxPerPage), igual en todos los endpoints
+func (srv *server) parsePagination(r *http.Request) (Pagination, *apiError) {
+	query := r.URL.Query()
+	p := Pagination{number: 1, size: defaultPerPage, baseURL: srv.requestBaseURL(r)}
+	if !query.Has("page") && !query.Has("per_page") {
+		return p, nil
+	}
+	p.enabled = true
+
+	if query.Has("page") {
+		n, err := strconv.Atoi(query.Get("page"))
+		if err != nil {
+			return p, newAPIError(http.StatusBadRequest, codeBadRequest, "Invalid page: must be a positive integer")
+		}
+		p.number = max(n, 1)
+	}
+	if query.Has("per_page") {
+		n, err := strconv.Atoi(query.Get("per_page"))
+		if err != nil {
+			return p, newAPIError(http.StatusBadRequest, codeBadRequest, "Invalid per_page: must be between 1 and %d", maxPerPage)
+		}
+		p.size = min(max(n, 1), maxPerPage)
+	}
+	return p, nil
+}
+
+// Fijar X-Total-Count y, si se pidió una página, Link; devuelve los
+// elementos de la página
+func applyPagination[T any](w http.ResponseWriter, r *http.Request, p Pagination, list []T) []T {
+	setPaginationHeaders(w, r, p, len(list))
+	return pageOf(p, list)
+}
+
+// Cabeceras de paginación para total elementos, para quien solo cuenta
+func setPaginationHeaders(w http.ResponseWriter, r *http.Request, p Pagination, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if p.enabled {
+		w.Header().Set("Link", p.links(r, total))
+	}
+}
+
+// Número de la última página para total elementos (al menos 1)
+func (p Pagination) last(total int) int {
+	return max(1, (total+p.size-1)/p.size)
+}
+
+// Elementos de la página pedida; una página fuera de rango queda vacía
+func pageOf[T any](p Pagination, list []T) []T {
+	if !p.enabled {
+		return list
+	}
+	start := min((p.number-1)*p.size, len(list))
+	end := min(start+p.size, len(list))
+	return list[start:end]
+}
+
+// Cabecera Link (RFC 8288) con first, prev, next y last como URLs absolutas
+// que conservan el resto de la query; prev y next se omiten en los extremos
+func (p Pagination) links(r *http.Request, total int) string {
+	last := p.last(total)
+	pageURL := func(n int) string {
+		query := r.URL.Query()
+		query.Set("page", strconv.Itoa(n))
+		query.Set("per_page", strconv.Itoa(p.size))
+		return p.baseURL + r.URL.Path + "?" + query.Encode()
+	}
+
+	links := []string{fmt.Sprintf(`<%s>; rel="first"`, pageURL(1))}
+	if p.number > 1 {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(min(p.number-1, last))))
+	}
+	if p.number < last {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(p.number+1)))
+	}
+	links = append(links, fmt.Sprintf(`<%s>; rel="last"`, pageURL(last)))
+	return strings.Join(links, ", ")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Endpoints de listado que comparten la paginación
+var paginatedEndpoints = []struct{ method, path string }{
+	{"GET", "/api/users"},
+	{"HEAD", "/api/users"},
+	{"GET", "/api/users/export?format=ndjson"},
+}
+
+// Número de usuarios devueltos en el cuerpo; HEAD no lleva cuerpo
+func pageLength(t *testing.T, path string, data []byte) int {
+	t.Helper()
+	if strings.HasPrefix(path, "/api/users/export") {
+		return strings.Count(string(data), "\n")
+	}
+	var list struct {
+		Data []User `json:"data"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		t.Fatal(err)
+	}
+	return len(list.Data)
+}
+
+func TestPaginationIsConsistentAcrossEndpoints(t *testing.T) {
+	_, ts := newTestServer(t, memoryStore{}, nil)
+	for _, name := range []string{"Ana", "Bea", "Eva"} {
+		createUser(t, ts, name+" Ruiz", strings.ToLower(name)+"@example.com")
+	}
+
+	cases := []struct {
+		query   string
+		want    int
+		wantRel []string
+		// Página y tamaño que deben aparecer en el enlace last
+		wantLast string
+	}{
+		{"page=2&per_page=2", 2, []string{"first", "prev", "next", "last"}, "page=3&per_page=2"},
+		{"page=0&per_page=2", 2, []string{"first", "next", "last"}, "page=3&per_page=2"},
+		{"per_page=500", 5, []string{"first", "last"}, "page=1&per_page=100"},
+		{"page=9&per_page=2", 0, []string{"first", "prev", "last"}, "page=3&per_page=2"},
+	}
+	for _, tc := range cases {
+		var links []string
+		for _, endpoint := range paginatedEndpoints {
+			sep := "?"
+			if strings.Contains(endpoint.path, "?") {
+				sep = "&"
+			}
+			path := endpoint.path + sep + tc.query
+			resp, data := doRequest(t, ts, endpoint.method, path, "")
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("%s %s: status %d: %s", endpoint.method, path, resp.StatusCode, data)
+			}
+			if got := resp.Header.Get("X-Total-Count"); got != "5" {
+				t.Errorf("%s %s: X-Total-Count %q, want 5", endpoint.method, path, got)
+			}
+			link := resp.Header.Get("Link")
+			for _, rel := range tc.wantRel {
+				if !strings.Contains(link, `rel="`+rel+`"`) {
+					t.Errorf("%s %s: Link %q has no rel=%q", endpoint.method, path, link, rel)
+				}
+			}
+			if !strings.Contains(link, tc.wantLast+`>; rel="last"`) {
+				t.Errorf("%s %s: Link %q, want last page %s", endpoint.method, path, link, tc.wantLast)
+			}
+			if endpoint.method != "HEAD" {
+				if got := pageLength(t, path, data); got != tc.want {
+					t.Errorf("%s %s: %d users, want %d", endpoint.method, path, got, tc.want)
+				}
+			}
+			// Los enlaces solo difieren en la ruta y en ?format
+			link = strings.ReplaceAll(link, "/api/users/export", "/api/users")
+			link = strings.ReplaceAll(link, "format=ndjson&", "")
+			links = append(links, link)
+		}
+		for i := 1; i < len(links); i++ {
+			if links[i] != links[0] {
+				t.Errorf("?%s: %s %s gave Link %q, %s %s gave %q", tc.query,
+					paginatedEndpoints[0].method, paginatedEndpoints[0].path, links[0],
+					paginatedEndpoints[i].method, paginatedEndpoints[i].path, links[i])
+			}
+		}
+	}
+
+	for _, query := range []string{"page=abc", "per_page=1.5"} {
+		for _, endpoint := range paginatedEndpoints {
+			sep := "?"
+			if strings.Contains(endpoint.path, "?") {
+				sep = "&"
+			}
+			resp, _ := doRequest(t, ts, endpoint.method, endpoint.path+sep+query, "")
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("%s %s%s%s: status %d, want 400", endpoint.method, endpoint.path, sep, query, resp.StatusCode)
+			}
+		}
+	}
+}
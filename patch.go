@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// Campos que no se pueden modificar una vez creado el usuario
+var immutableUserFields = []string{"id", "created_at", "deleted_at"}
+
+// Comprobar que el cuerpo no intenta cambiar campos inmutables; repetir el
+// valor actual está permitido. Devuelve el campo infractor o "".
+func checkImmutableFields(fields map[string]json.RawMessage, current User) string {
+	for _, name := range immutableUserFields {
+		raw, present := fields[name]
+		if !present {
+			continue
+		}
+
+		changed := true
+		switch name {
+		case "id":
+			var id int
+			changed = json.Unmarshal(raw, &id) != nil || id != current.ID
+		case "created_at":
+			var createdAt time.Time
+			changed = json.Unmarshal(raw, &createdAt) != nil || !createdAt.Equal(current.CreatedAt)
+		case "deleted_at":
+			var deletedAt *time.Time
+			changed = json.Unmarshal(raw, &deletedAt) != nil || deletedAt != nil
+		}
+		if changed {
+			return name
+		}
+	}
+	return ""
+}
+
+// Actualizar parcialmente un usuario con los campos presentes en el cuerpo
+func (srv *server) patchUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := srv.parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	body, ok := srv.readJSONBody(w, r)
+	if !ok {
+		return
+	}
+	var fields map[string]json.RawMessage
+	if !srv.unmarshalJSONBody(w, r, body, &fields) {
+		return
+	}
+
+	srv.usersMu.Lock()
+	defer srv.usersMu.Unlock()
+
+	i := indexOfUser(srv.users, id)
+	if i < 0 {
+		srv.writeError(w, r, http.StatusNotFound, codeNotFound, "User not found")
+		return
+	}
+	user := srv.users[i]
+
+	if field := checkImmutableFields(fields, user); field != "" {
+		srv.writeError(w, r, http.StatusBadRequest, codeImmutableField, "Field %q is immutable and cannot be modified", field)
+		return
+	}
+
+	// Unmarshal sobre una copia solo sobrescribe los campos presentes
+	patched := user
+	if !srv.unmarshalJSONBody(w, r, body, &patched) {
+		return
+	}
+	patched.ID = user.ID
+	patched.CreatedAt = user.CreatedAt
+
+	srv.normalizeUser(&patched)
+	if errs := srv.validateUserFields(patched); len(errs) > 0 {
+		srv.writeValidationErrors(w, r, errs)
+		return
+	}
+	if patched.Role != user.Role && !srv.isAdmin(r) {
+		srv.writeError(w, r, http.StatusForbidden, codeForbidden, "Only admins can change roles")
+		return
+	}
+
+	if field := srv.findUniqueConflict(srv.users, patched, user.ID); field != "" {
+		srv.writeUniqueConflict(w, r, field, patched)
+		return
+	}
+
+	keepVerification(&patched, user)
+	patched.UpdatedAt = time.Now().UTC()
+	next := slices.Clone(srv.users)
+	next[i] = patched
+	if !srv.commitUsers(w, r, next) {
+		return
+	}
+
+	srv.writeUserResult(w, r, http.StatusOK, "User updated successfully", patched)
+}
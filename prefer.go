@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Valores de la preferencia return (RFC 7240 §4.2)
+const (
+	preferMinimal        = "minimal"
+	preferRepresentation = "representation"
+)
+
+// Preferencia return de la cabecera Prefer, o "" si no hay ninguna válida
+func preferredReturn(r *http.Request) string {
+	for _, header := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(header, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(pref), "=")
+			if !strings.EqualFold(strings.TrimSpace(name), "return") {
+				continue
+			}
+			value = strings.ToLower(strings.Trim(strings.TrimSpace(value), `"`))
+			if value == preferMinimal || value == preferRepresentation {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// Responder a una creación (201) o actualización (200) de un usuario. Las
+// creaciones llevan siempre Location. Con Prefer: return=minimal no se
+// devuelve el usuario: 201 sin cuerpo o 204. La preferencia aplicada se
+// indica en Preference-Applied.
+func (srv *server) writeUserResult(w http.ResponseWriter, r *http.Request, status int, message string, user User) {
+	if status == http.StatusCreated {
+		w.Header().Set("Location", "/api/users/"+strconv.Itoa(user.ID))
+	}
+	w.Header().Add("Vary", "Prefer")
+
+	switch preferredReturn(r) {
+	case preferMinimal:
+		w.Header().Set("Preference-Applied", "return="+preferMinimal)
+		if status == http.StatusOK {
+			status = http.StatusNoContent
+		}
+		w.WriteHeader(status)
+		return
+	case preferRepresentation:
+		w.Header().Set("Preference-Applied", "return="+preferRepresentation)
+	}
+
+	srv.writeJSON(w, status, Response{
+		Status:  "success",
+		Message: message,
+		Data:    srv.userData(r, user),
+	})
+}
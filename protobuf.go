@@ -0,0 +1,59 @@
+package main
+
+//go:generate buf generate
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"microservicio-basico/userpb"
+)
+
+// Tipo de medio con el que el cliente pide protobuf (ver user.proto)
+const protobufMediaType = "application/x-protobuf"
+
+// Mensaje User de un usuario
+func userProto(user User) *userpb.User {
+	msg := &userpb.User{
+		Id:            int64(user.ID),
+		Name:          user.Name,
+		Email:         user.Email,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified,
+		CreatedAt:     timestamppb.New(user.CreatedAt),
+		UpdatedAt:     timestamppb.New(user.UpdatedAt),
+		Tags:          user.Tags,
+	}
+	if user.DeletedAt != nil {
+		msg.DeletedAt = timestamppb.New(*user.DeletedAt)
+	}
+	return msg
+}
+
+// Mensaje UserList de una lista de usuarios
+func userListProto(list []User) *userpb.UserList {
+	msg := &userpb.UserList{Users: make([]*userpb.User, 0, len(list))}
+	for _, user := range list {
+		msg.Users = append(msg.Users, userProto(user))
+	}
+	return msg
+}
+
+// Responder con un mensaje protobuf. Se codifica entero antes de escribir
+// nada, así que un error de codificación se responde como 500.
+func (srv *server) writeProtobuf(w http.ResponseWriter, r *http.Request, status int, msg proto.Message) {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		log.Printf("ERROR: could not encode protobuf response: %v", err)
+		srv.writeError(w, r, http.StatusInternalServerError, codeInternalError, "Internal server error")
+		return
+	}
+	w.Header().Set("Content-Type", protobufMediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	w.Write(data)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// Instante de ?before: una fecha RFC 3339 o, con solo el día (2024-01-01),
+// la medianoche UTC de ese día
+func parsePurgeBefore(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return parsed, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// Eliminar definitivamente los usuarios borrados lógicamente. Con
+// ?before=<RFC 3339 o YYYY-MM-DD> solo se purgan los eliminados antes de esa
+// fecha; sin él se purgan todos. El siguiente ID se conserva en el archivo de datos,
+// así que los IDs purgados no se reutilizan.
+func (srv *server) purgeUsersHandler(w http.ResponseWriter, r *http.Request) {
+	var before time.Time
+	hasBefore := r.URL.Query().Has("before")
+	if hasBefore {
+		parsed, err := parsePurgeBefore(r.URL.Query().Get("before"))
+		if err != nil {
+			srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "Invalid before: must be an RFC 3339 timestamp or a date (YYYY-MM-DD)")
+			return
+		}
+		before = parsed
+	}
+
+	srv.usersMu.Lock()
+	defer srv.usersMu.Unlock()
+
+	next := slices.DeleteFunc(slices.Clone(srv.users), func(u User) bool {
+		return u.DeletedAt != nil && (!hasBefore || u.DeletedAt.Before(before))
+	})
+	purged := len(srv.users) - len(next)
+
+	if purged > 0 && !srv.commitUsers(w, r, next) {
+		return
+	}
+
+	srv.writeJSON(w, http.StatusOK, Response{
+		Status:  "success",
+		Message: fmt.Sprintf("%d deleted users purged", purged),
+		Data:    map[string]int{"purged": purged},
+	})
+}
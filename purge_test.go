@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPurgeBeforeAcceptsDateOnly(t *testing.T) {
+	_, ts := newTestServer(t, memoryStore{}, nil)
+	id := createUser(t, ts, "Ana Ruiz", "ana@example.com")
+	if resp, data := doRequest(t, ts, "DELETE", "/api/users/"+strconv.Itoa(id), ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("DELETE: status %d: %s", resp.StatusCode, data)
+	}
+
+	purge := func(before string) (int, int) {
+		t.Helper()
+		resp, data := doRequest(t, ts, "DELETE", "/api/users/purge?before="+before, "")
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, 0
+		}
+		purged, _ := decodeResponse(t, data).Data.(map[string]interface{})["purged"].(float64)
+		return resp.StatusCode, int(purged)
+	}
+
+	// Medianoche UTC de un día pasado: el usuario se eliminó después
+	if status, purged := purge("2024-01-01"); status != http.StatusOK || purged != 0 {
+		t.Errorf("before=2024-01-01: status %d, purged %d; want 200 and 0", status, purged)
+	}
+	if status, _ := purge("2024-13-01"); status != http.StatusBadRequest {
+		t.Errorf("before=2024-13-01: status %d, want 400", status)
+	}
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+	if status, purged := purge(tomorrow); status != http.StatusOK || purged != 1 {
+		t.Errorf("before=%s: status %d, purged %d; want 200 and 1", tomorrow, status, purged)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Parámetros de query de los filtros de usuarios (ver parseUserFilter)
+func userFilterParams() []string {
+	params := []string{"modified_since", "verified", "tag", "tag_match"}
+	for field := range optionalUserFields {
+		params = append(params, "has_"+field)
+	}
+	return params
+}
+
+// Parámetros que reconoce cada endpoint de listado o búsqueda, con las
+// mismas claves que ROUTE_HEADERS. Al añadir un filtro hay que añadirlo
+// aquí o STRICT_QUERY_PARAMS lo rechazará.
+var knownQueryParams = map[string][]string{
+	"GET /api/users":              append(userFilterParams(), "page", "per_page"),
+	"HEAD /api/users":             append(userFilterParams(), "page", "per_page"),
+	"GET /api/users/export":       append(userFilterParams(), "page", "per_page", "format", "cursor"),
+	"GET /api/users/batch":        {"ids", "id", "strict"},
+	"GET /api/users/{id}/history": {"page", "per_page"},
+	"GET " + emailAvailablePath:   {"email"},
+}
+
+// Middleware de la flag strict-query (STRICT_QUERY_PARAMS la activa por
+// defecto): responde 400 con los parámetros que el endpoint no reconoce, en
+// lugar de ignorarlos y devolver resultados sin filtrar. Las rutas sin lista
+// en knownQueryParams no se comprueban.
+func (srv *server) strictQueryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !srv.FeatureEnabled(r.Context(), flagStrictQuery) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		template, _ := routeTemplate(r)
+		known, ok := knownQueryParams[r.Method+" "+template]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var unknown []string
+		for name := range r.URL.Query() {
+			if !slices.Contains(known, name) {
+				unknown = append(unknown, name)
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "Unknown query parameters: %s", strings.Join(unknown, ", "))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Límite de una API key en RATE_LIMIT_KEYS
+type RateLimit struct {
+	RPS   float64 `json:"rps"`
+	Burst int     `json:"burst"`
+}
+
+// Cubeta de tokens de un cliente: los créditos de ráfaga se recargan a
+// RateLimitRPS por segundo hasta RateLimitBurst
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limitador de peticiones por cliente
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*tokenBucket
+	// Límites propios por clave de rateLimitKey, en lugar de rate y burst
+	overrides map[string]RateLimit
+	// Peticiones que este limitador no cuenta (tienen uno propio)
+	exempt func(r *http.Request) bool
+}
+
+// Crear un limitador cuya limpieza periódica termina al cerrarse stop
+func newRateLimiter(rate float64, burst int, stop <-chan struct{}) *rateLimiter {
+	rl := &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+	go rl.cleanup(stop)
+	return rl
+}
+
+// Ritmo y ráfaga que se aplican a una clave
+func (rl *rateLimiter) limitFor(key string) (rate, burst float64) {
+	if limit, ok := rl.overrides[key]; ok {
+		return limit.RPS, float64(limit.Burst)
+	}
+	return rl.rate, rl.burst
+}
+
+// Consumir un token; devuelve si se permite la petición, los tokens
+// restantes y cuándo volverá a estar llena la cubeta
+func (rl *rateLimiter) allow(key string) (bool, int, time.Time) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rate, burst := rl.limitFor(key)
+	now := time.Now()
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: burst, last: now}
+		rl.buckets[key] = b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	reset := now.Add(time.Duration((burst - b.tokens) / rate * float64(time.Second)))
+	return allowed, int(b.tokens), reset
+}
+
+// Eliminar periódicamente las cubetas que ya se han recargado por completo,
+// hasta que se cierre stop
+func (rl *rateLimiter) cleanup(stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+		rl.mu.Lock()
+		for key, b := range rl.buckets {
+			rate, burst := rl.limitFor(key)
+			if time.Since(b.last) > time.Duration(burst/rate*float64(time.Second)) {
+				delete(rl.buckets, key)
+			}
+		}
+		rl.mu.Unlock()
+	}
+}
+
+// Clave con la que se limita a un cliente: su API key si es válida o,
+// en su defecto, su IP. Se calcula antes de auth, así que una key inválida
+// cuenta contra la IP.
+func (srv *server) rateLimitKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		if _, ok := srv.config.APIKeys[key]; ok {
+			return "key:" + key
+		}
+	}
+	return "ip:" + srv.ClientIP(r)
+}
+
+// Middleware de límite de peticiones
+func (srv *server) rateLimitMiddleware(rl *rateLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if rl.exempt != nil && rl.exempt(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key := srv.rateLimitKey(r)
+			allowed, remaining, reset := rl.allow(key)
+			rate, burst := rl.limitFor(key)
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(int(burst)))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+
+			if !allowed {
+				// Tiempo hasta que se repone el siguiente token
+				setRetryAfter(w, time.Duration(float64(time.Second)/rate))
+				srv.writeError(w, r, http.StatusTooManyRequests, codeRateLimited, "Rate limit exceeded")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestRateLimitThrottlesInvalidAPIKeys(t *testing.T) {
+	_, ts := newTestServer(t, memoryStore{}, func(cfg *Config) {
+		cfg.APIKeys = map[string]int{"secret-key-123": 1}
+		cfg.RateLimitRPS = 0.5
+		cfg.RateLimitBurst = 3
+	})
+
+	var last *http.Response
+	for i := 0; i < 5; i++ {
+		resp, _ := doRequest(t, ts, "GET", "/api/users", "", "X-API-Key", "guess-"+strconv.Itoa(i))
+		if resp.StatusCode == http.StatusUnauthorized && resp.Header.Get("X-RateLimit-Limit") == "" {
+			t.Errorf("request %d: 401 without X-RateLimit-Limit", i+1)
+		}
+		last = resp
+	}
+	if last.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("after 5 requests with invalid keys: status %d, want 429", last.StatusCode)
+	}
+	retryAfterSeconds(t, last)
+
+	// La key válida tiene su propia cubeta
+	if resp, data := doRequest(t, ts, "GET", "/api/users", "", "X-API-Key", "secret-key-123"); resp.StatusCode != http.StatusOK {
+		t.Errorf("valid key after the IP was throttled: status %d, want 200: %s", resp.StatusCode, data)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Modos de LOG_REDACT para los datos personales que acaban en el log:
+// off los deja como están, mask los sustituye por [redacted] y hash por un
+// resumen corto que permite correlacionar líneas sin revelar el valor
+const (
+	redactOff  = "off"
+	redactMask = "mask"
+	redactHash = "hash"
+)
+
+// Direcciones de email en texto libre
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// Campos cuyo valor se redacta cuando aparecen en el log como "campo":"valor"
+// (JSON) o campo=valor (query o texto); el nombre no se distingue por
+// mayúsculas
+var sensitiveLogFields = []string{"name", "email", "api_key", "x-api-key", "password", "token"}
+
+var (
+	sensitiveJSONPattern  = regexp.MustCompile(`(?i)("(?:` + strings.Join(sensitiveLogFields, "|") + `)"\s*:\s*")((?:[^"\\]|\\.)*)"`)
+	sensitiveParamPattern = regexp.MustCompile(`(?i)\b(` + strings.Join(sensitiveLogFields, "|") + `)=([^&\s"]+)`)
+)
+
+// Longitud mínima de una API key para buscarla literalmente en el log; más
+// corta reemplazaría fragmentos de texto normal
+const minRedactedKeyLength = 8
+
+// Valor redactado según el modo; con hash, kind indica qué era
+func redactValue(kind, value, mode string) string {
+	if mode == redactHash {
+		sum := sha256.Sum256([]byte(strings.ToLower(value)))
+		return kind + ":" + hex.EncodeToString(sum[:4])
+	}
+	return "[redacted]"
+}
+
+// Redactar los datos sensibles de un texto según el modo: las API keys
+// configuradas (apiKeys), los valores de los campos de sensitiveLogFields y
+// los emails
+func redactText(s, mode string, apiKeys map[string]int) string {
+	if mode != redactMask && mode != redactHash {
+		return s
+	}
+	for key := range apiKeys {
+		if len(key) >= minRedactedKeyLength && strings.Contains(s, key) {
+			s = strings.ReplaceAll(s, key, redactValue("key", key, mode))
+		}
+	}
+	s = sensitiveJSONPattern.ReplaceAllStringFunc(s, func(match string) string {
+		parts := sensitiveJSONPattern.FindStringSubmatch(match)
+		field := strings.ToLower(strings.Trim(strings.SplitN(parts[1], ":", 2)[0], `" `))
+		return parts[1] + redactValue(field, parts[2], mode) + `"`
+	})
+	s = sensitiveParamPattern.ReplaceAllStringFunc(s, func(match string) string {
+		parts := sensitiveParamPattern.FindStringSubmatch(match)
+		return parts[1] + "=" + redactValue(strings.ToLower(parts[1]), parts[2], mode)
+	})
+	return emailPattern.ReplaceAllStringFunc(s, func(email string) string {
+		return redactValue("email", email, mode)
+	})
+}
+
+// Salida del log que redacta cada línea antes de escribirla; se instala
+// con log.SetOutput para que cubra todos los mensajes
+type redactingWriter struct {
+	out     io.Writer
+	mode    string
+	apiKeys map[string]int
+}
+
+func (rw redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(rw.out, redactText(string(p), rw.mode, rw.apiKeys)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
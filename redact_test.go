@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Buffer seguro para el log, que escriben las goroutines del servidor
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRedactTextRemovesEmails(t *testing.T) {
+	lines := []string{
+		`Started GET /api/users/ana@example.com from 127.0.0.1`,
+		`WARN: email Ana.Ruiz+test@Example.co.uk is shared by users [3 4]`,
+		`body {"name":"Ana Ruiz","email":"ana@example.com"}`,
+		`GET /api/users/email-available?email=ana%40example.com`,
+	}
+	for _, mode := range []string{redactMask, redactHash} {
+		for _, line := range lines {
+			got := redactText(line, mode, nil)
+			if strings.Contains(strings.ToLower(got), "example.co") || strings.Contains(got, "Ana Ruiz") {
+				t.Errorf("redactText(%q, %s) = %q still contains personal data", line, mode, got)
+			}
+		}
+	}
+}
+
+func TestEmailsNeverReachTheLog(t *testing.T) {
+	const apiKey = "secret-key-123"
+	apiKeys := map[string]int{apiKey: 1}
+	var out syncBuffer
+	log.SetOutput(redactingWriter{out: &out, mode: redactHash, apiKeys: apiKeys})
+	defer log.SetOutput(io.Discard)
+
+	srv, ts := newTestServer(t, memoryStore{}, func(cfg *Config) {
+		cfg.LogLevel = "debug"
+		cfg.APIKeys = apiKeys
+	})
+	// El análisis de integridad escribe los emails repetidos
+	srv.users = append(srv.users, User{ID: 3, Name: "Juan P.", Email: "juan@example.com", Role: roleMember})
+	srv.checkDataIntegrity()
+
+	auth := []string{"X-API-Key", apiKey}
+	doRequest(t, ts, "GET", "/api/users/email-available?email=ana@example.com", "", auth...)
+	doRequest(t, ts, "GET", "/api/users/ana@example.com", "", auth...)
+	doRequest(t, ts, "POST", "/api/users", `{"name":"Ana Ruiz","email":"juan@example.com"}`, auth...)
+	doRequest(t, ts, "GET", "/api/users?email=juan@example.com", "", auth...)
+
+	logged := out.String()
+	if logged == "" {
+		t.Fatal("nothing was logged")
+	}
+	for _, secret := range []string{"ana@example.com", "juan@example.com", apiKey} {
+		if strings.Contains(logged, secret) {
+			t.Errorf("log contains %q in plaintext:\n%s", secret, logged)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Nonces ya usados y hasta cuándo se recuerdan; cada uno se recuerda ttl
+type nonceCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	seen      map[string]time.Time
+	lastPurge time.Time
+}
+
+// Registrar un nonce; devuelve false si ya se usó y no ha caducado
+func (c *nonceCache) add(key string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Limpiar los caducados como mucho una vez por TTL
+	if now.Sub(c.lastPurge) > c.ttl {
+		for k, expires := range c.seen {
+			if now.After(expires) {
+				delete(c.seen, k)
+			}
+		}
+		c.lastPurge = now
+	}
+
+	if expires, ok := c.seen[key]; ok && now.Before(expires) {
+		return false
+	}
+	c.seen[key] = now.Add(c.ttl)
+	return true
+}
+
+// Los métodos que modifican datos son los que se protegen
+func isMutating(method string) bool {
+	return method == "POST" || method == "PUT" || method == "PATCH" || method == "DELETE"
+}
+
+// Middleware de protección frente a peticiones repetidas (REPLAY_PROTECTION).
+// Las peticiones que modifican datos deben llevar X-Request-Nonce, de un
+// solo uso, y X-Request-Timestamp en segundos Unix, a no más de
+// MAX_CLOCK_SKEW del reloj del servidor. Un nonce repetido responde 409.
+func (srv *server) replayProtectionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isMutating(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		nonce := r.Header.Get("X-Request-Nonce")
+		if nonce == "" || len(nonce) > 128 {
+			srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "X-Request-Nonce is required and must be at most 128 characters")
+			return
+		}
+		seconds, err := strconv.ParseInt(r.Header.Get("X-Request-Timestamp"), 10, 64)
+		if err != nil {
+			srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "X-Request-Timestamp must be a Unix timestamp in seconds")
+			return
+		}
+		now := time.Now()
+		if skew := now.Sub(time.Unix(seconds, 0)); skew > srv.config.MaxClockSkew || skew < -srv.config.MaxClockSkew {
+			srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "X-Request-Timestamp is outside the allowed clock skew of %v", srv.config.MaxClockSkew)
+			return
+		}
+
+		// Cada cliente tiene su propio espacio de nonces
+		if !srv.usedNonces.add(srv.rateLimitKey(r)+"\x00"+nonce, now) {
+			srv.writeError(w, r, http.StatusConflict, codeConflict, "Request nonce has already been used")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"syscall"
+	"time"
+)
+
+// Límite del tiempo de espera entre reintentos
+const maxRetryDelay = 2 * time.Second
+
+// Espera total máxima entre reintentos de una escritura con usersMu
+// bloqueado, que retiene a todas las peticiones mientras dura
+const lockedRetryBudget = 250 * time.Millisecond
+
+// Clasificar un error del store como transitorio: los backends pueden
+// marcarlos con Temporary() (como net.Error) y para el archivo se
+// consideran transitorios los errores de recurso ocupado o interrumpido
+func isTransientError(err error) bool {
+	var temporary interface{ Temporary() bool }
+	if errors.As(err, &temporary) {
+		return temporary.Temporary()
+	}
+	return errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EBUSY) ||
+		errors.Is(err, syscall.EINTR) || errors.Is(err, syscall.ETIMEDOUT)
+}
+
+// Ejecutar op reintentando los errores transitorios hasta attempts intentos,
+// con espera exponencial y jitter completo entre ellos. Con budget > 0 la
+// espera total no lo supera: se deja de reintentar al agotarlo.
+func (srv *server) withRetry(name string, attempts int, budget time.Duration, op func() error) error {
+	var err error
+	var waited time.Duration
+	for attempt := 1; ; attempt++ {
+		err = op()
+		if err == nil || attempt >= attempts || !isTransientError(err) {
+			return err
+		}
+
+		delay := min(srv.config.StoreRetryBaseDelay<<(attempt-1), maxRetryDelay)
+		if budget > 0 {
+			if waited >= budget {
+				return err
+			}
+			delay = min(delay, budget-waited)
+		}
+		delay = time.Duration(rand.Int63n(int64(delay) + 1))
+		waited += delay
+		log.Printf("WARN: store %s failed (attempt %d/%d), retrying in %v: %v", name, attempt, attempts, delay.Round(time.Millisecond), err)
+		time.Sleep(delay)
+	}
+}
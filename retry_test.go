@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Error que el store marca como transitorio
+type transientError struct{}
+
+func (transientError) Error() string   { return "store is busy" }
+func (transientError) Temporary() bool { return true }
+
+// Store en memoria cuyas primeras failures escrituras fallan con un error
+// transitorio
+type flakyStore struct {
+	mu       sync.Mutex
+	failures int
+	saves    int
+	saved    storeData
+}
+
+func (s *flakyStore) Name() string { return "flaky" }
+
+func (s *flakyStore) Load() (storeData, bool, error) { return storeData{}, false, nil }
+
+func (s *flakyStore) Save(snapshot storeData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.saves++
+	if s.saves <= s.failures {
+		return transientError{}
+	}
+	s.saved = snapshot
+	return nil
+}
+
+func (s *flakyStore) Ping(ctx context.Context) error { return nil }
+
+func (s *flakyStore) saveCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saves
+}
+
+// Reintentos rápidos y sin breaker salvo que el test lo active
+func fastRetries(cfg *Config) {
+	cfg.StoreRetryAttempts = 3
+	cfg.StoreRetryBaseDelay = time.Millisecond
+	cfg.BreakerThreshold = 0
+}
+
+func TestAsyncFlushRetriesTransientErrors(t *testing.T) {
+	store := &flakyStore{failures: 2}
+	srv, ts := newTestServer(t, store, func(cfg *Config) {
+		fastRetries(cfg)
+		cfg.PersistMode = "async"
+	})
+
+	createUser(t, ts, "Ana Ruiz", "ana@example.com")
+	if !srv.flushUsers() {
+		t.Fatal("flushUsers failed despite only transient errors within the retry budget")
+	}
+	if got := store.saveCount(); got != 3 {
+		t.Errorf("store saw %d saves, want 3 (two failures and a success)", got)
+	}
+	if len(store.saved.Users) != 3 {
+		t.Errorf("saved %d users, want 3", len(store.saved.Users))
+	}
+	if srv.degraded.Load() {
+		t.Error("server is degraded after a successful retry")
+	}
+}
+
+func TestSyncSaveRetriesTransientErrors(t *testing.T) {
+	store := &flakyStore{failures: 1}
+	srv, ts := newTestServer(t, store, fastRetries)
+
+	resp, data := doRequest(t, ts, "POST", "/api/users", `{"name":"Ana Ruiz","email":"ana@example.com"}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST after a transient store error: status %d, want 201: %s", resp.StatusCode, data)
+	}
+	if got := store.saveCount(); got != 2 {
+		t.Errorf("store saw %d saves, want 2 (a failure and a success)", got)
+	}
+	if srv.degraded.Load() {
+		t.Error("server is degraded after a successful retry")
+	}
+}
+
+func TestSyncSaveRetriesWithinLockedBudget(t *testing.T) {
+	store := &flakyStore{failures: 100}
+	_, ts := newTestServer(t, store, func(cfg *Config) {
+		fastRetries(cfg)
+		// Sin el límite esperaría hasta maxRetryDelay por reintento
+		cfg.StoreRetryAttempts = 10
+		cfg.StoreRetryBaseDelay = time.Second
+	})
+
+	start := time.Now()
+	resp, data := doRequest(t, ts, "POST", "/api/users", `{"name":"Ana Ruiz","email":"ana@example.com"}`)
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("POST with a failing store: status %d, want 503: %s", resp.StatusCode, data)
+	}
+	if elapsed := time.Since(start); elapsed > lockedRetryBudget+500*time.Millisecond {
+		t.Errorf("synchronous save held usersMu for %v, want at most about %v", elapsed, lockedRetryBudget)
+	}
+}
+
+func TestBreakerStopsCallingFlakyStore(t *testing.T) {
+	store := &flakyStore{failures: 100}
+	_, ts := newTestServer(t, store, func(cfg *Config) {
+		fastRetries(cfg)
+		cfg.BreakerThreshold = 2
+		cfg.BreakerCooldown = time.Minute
+	})
+
+	for i := 0; i < 3; i++ {
+		resp, data := doRequest(t, ts, "POST", "/api/users", `{"name":"Ana Ruiz","email":"ana@example.com"}`)
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("POST %d: status %d, want 503: %s", i+1, resp.StatusCode, data)
+		}
+		if resp.Header.Get("Retry-After") == "" {
+			t.Errorf("POST %d: 503 without Retry-After", i+1)
+		}
+	}
+	// Los reintentos de cada escritura cuentan como un fallo
+	if got, want := store.saveCount(), 2*3; got != want {
+		t.Errorf("store saw %d saves, want %d: the open breaker must reject the third write", got, want)
+	}
+}
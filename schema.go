@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Campos obligatorios que comprueba validateUser
+var requiredUserFields = []string{"name", "email"}
+
+// Longitudes máximas (en caracteres) que comprueba validateUser; 254 es el
+// máximo práctico de una dirección de email (RFC 5321)
+const (
+	maxNameLength  = 100
+	maxEmailLength = 254
+)
+
+// JSON Schema del tipo User, construido a partir de las mismas reglas que
+// aplica validateUser
+func (srv *server) userSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"$schema":  "https://json-schema.org/draft/2020-12/schema",
+		"$id":      "/api/schema/user",
+		"title":    "User",
+		"type":     "object",
+		"required": requiredUserFields,
+		"properties": map[string]interface{}{
+			"id": map[string]interface{}{
+				"type":     "integer",
+				"minimum":  1,
+				"readOnly": true,
+			},
+			"name": map[string]interface{}{
+				"type":      "string",
+				"minLength": 1,
+				"maxLength": maxNameLength,
+			},
+			"email": map[string]interface{}{
+				"type":      "string",
+				"format":    "email",
+				"minLength": 1,
+				"maxLength": maxEmailLength,
+			},
+			"role": srv.roleSchema(),
+			"tags": map[string]interface{}{
+				"type":        "array",
+				"maxItems":    maxTags,
+				"uniqueItems": true,
+				"items": map[string]interface{}{
+					"type":      "string",
+					"maxLength": maxTagLength,
+					"pattern":   tagPattern.String(),
+				},
+			},
+			"email_verified": map[string]interface{}{
+				"type":     "boolean",
+				"default":  false,
+				"readOnly": true,
+			},
+			"created_at": map[string]interface{}{
+				"type":     "string",
+				"format":   "date-time",
+				"readOnly": true,
+			},
+			"updated_at": map[string]interface{}{
+				"type":     "string",
+				"format":   "date-time",
+				"readOnly": true,
+			},
+			"deleted_at": map[string]interface{}{
+				"type":     "string",
+				"format":   "date-time",
+				"readOnly": true,
+			},
+		},
+	}
+}
+
+// Esquema del rol; default es DEFAULT_ROLE, el que reciben los usuarios
+// nuevos sin rol
+func (srv *server) roleSchema() map[string]interface{} {
+	schema := map[string]interface{}{
+		"type": "string",
+		"enum": allowedRoles,
+	}
+	if srv.config.DefaultRole != "" {
+		schema["default"] = srv.config.DefaultRole
+	}
+	return schema
+}
+
+// Devolver el JSON Schema de User
+func (srv *server) userSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	json.NewEncoder(w).Encode(srv.userSchema())
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Comprobar la configuración junto con el entorno ya cargado (datos,
+// certificados) y resumirla en el log antes de abrir el puerto. Devuelve los
+// problemas que impiden arrancar; los arriesgados solo se avisan con WARN.
+func (srv *server) selfCheck() error {
+	var problems []error
+
+	if srv.authEnabled() {
+		hasAdmin := false
+		var missing []int
+		for key, id := range srv.config.APIKeys {
+			caller, ok := srv.userForAPIKey(key)
+			if !ok {
+				missing = append(missing, id)
+				continue
+			}
+			hasAdmin = hasAdmin || caller.Role == roleAdmin
+		}
+		if len(missing) > 0 {
+			sort.Ints(missing)
+			problems = append(problems, fmt.Errorf("API keys are mapped to users that do not exist: %v", missing))
+		}
+		if !hasAdmin {
+			log.Printf("WARN: no API key belongs to an admin; admin-only endpoints will always answer 403")
+		}
+		if srv.config.CORSAllowOrigin == "*" {
+			log.Printf("WARN: CORS allows any origin while API keys are enabled; set CORS_ALLOW_ORIGIN to the trusted origins")
+		}
+	} else {
+		log.Printf("WARN: authentication is disabled; every request is treated as an admin")
+	}
+
+	if srv.config.TLSCertFile != "" {
+		if _, err := tls.LoadX509KeyPair(srv.config.TLSCertFile, srv.config.TLSKeyFile); err != nil {
+			problems = append(problems, fmt.Errorf("TLS certificate %s and key %s cannot be loaded: %v", srv.config.TLSCertFile, srv.config.TLSKeyFile, err))
+		}
+	}
+	if srv.config.DebugErrors {
+		log.Printf("WARN: DEBUG_ERRORS is enabled; panic details and stack traces are sent to clients")
+	}
+	if srv.degraded.Load() {
+		log.Printf("WARN: data file %s is read-only; mutations will answer 503", srv.config.DataFile)
+	}
+	if srv.chaosEnabled() {
+		log.Printf("WARN: CHAOS TESTING IS ACTIVE: every request is delayed %dms and %.0f%% of them fail with 500; never run this in production", srv.config.ChaosLatencyMS, srv.config.ChaosErrorRate*100)
+	}
+
+	log.Printf("Self-check: %s", strings.Join(srv.diagnostics(), ", "))
+	return errors.Join(problems...)
+}
+
+// Resumen de la configuración efectiva para el log de arranque
+func (srv *server) diagnostics() []string {
+	storeStatus := srv.store.Name()
+	if srv.config.DataFile != "" {
+		mode := "writable"
+		if srv.degraded.Load() {
+			mode = "read-only"
+		}
+		storeStatus = fmt.Sprintf("%s %s (%s, %s persistence)", storeStatus, srv.config.DataFile, mode, srv.config.PersistMode)
+	}
+
+	auth := "disabled"
+	if srv.authEnabled() {
+		auth = fmt.Sprintf("%d API keys", len(srv.config.APIKeys))
+	}
+
+	tlsStatus := "off"
+	if srv.config.TLSCertFile != "" {
+		tlsStatus = "on (min " + tls.VersionName(srv.config.TLSMinVersion) + ")"
+	}
+
+	rateLimit := "off"
+	if srv.config.RateLimitRPS > 0 {
+		rateLimit = fmt.Sprintf("%g rps, burst %d", srv.config.RateLimitRPS, srv.config.RateLimitBurst)
+		if n := len(srv.config.RateLimitKeys); n > 0 {
+			rateLimit += fmt.Sprintf(" (%d per-key overrides)", n)
+		}
+	}
+
+	return []string{
+		"bind=:" + srv.config.Port,
+		"store=" + storeStatus,
+		"auth=" + auth,
+		"tls=" + tlsStatus,
+		"rate_limit=" + rateLimit,
+		"cors_origin=" + srv.config.CORSAllowOrigin,
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Estado de una instancia del servicio. Los handlers y middlewares son
+// métodos de server, así que dos servidores (p. ej. en tests en paralelo) no
+// comparten datos ni configuración.
+type server struct {
+	// Configuración activa del servicio
+	config Config
+	// Backend en el que se persisten los usuarios; sin DATA_FILE los datos
+	// solo viven en memoria
+	store Store
+	// Servidor HTTP con el router y los timeouts de config
+	http *http.Server
+
+	// Protege users y nextID frente a accesos concurrentes
+	usersMu sync.RWMutex
+	// Base de datos en memoria (en producción usarías una DB real)
+	users []User
+	// Estado de sequentialIDs (ver ids.go)
+	nextID int
+	// Generador activo
+	idGenerator IDGenerator
+
+	// Indica que el archivo de datos no se puede escribir
+	degraded atomic.Bool
+	// Hay cambios en memoria pendientes de escribir (modo async)
+	dirty bool
+	// Aviso al flusher de que hay cambios; con capacidad 1, las mutaciones
+	// que llegan mientras ya hay un aviso pendiente se agrupan en él
+	flushRequests chan struct{}
+	// Evita que una recarga se cuele entre la copia del estado y su
+	// escritura en el flush async. Se toma siempre antes que usersMu.
+	persistMu sync.Mutex
+	// Breaker de las escrituras al store; nil lo desactiva
+	storeBreaker *circuitBreaker
+	// Versión de los datos; cambia con cada mutación e invalida la caché
+	dataVersion atomic.Uint64
+
+	// Reglas de validación activas, en orden
+	validators []Validator
+	// Dependencias de readiness
+	readiness *HealthChecker
+	// Modo mantenimiento: se rechazan las escrituras y las lecturas siguen
+	// funcionando. Arranca con MAINTENANCE_MODE y se cambia en caliente con
+	// PUT /admin/maintenance.
+	maintenance atomic.Bool
+	// Historial de cambios de los usuarios
+	history *changeHistory
+	// Resultado del análisis de integridad hecho al arrancar, para /health
+	duplicateEmails []duplicateEmail
+	// Nonces ya usados por la protección contra repetición
+	usedNonces *nonceCache
+	// Reparto del log de accesos a /admin/logs/stream
+	accessLog *logBroadcaster
+
+	// El servidor está apagándose; readiness deja de declararse listo
+	shuttingDown atomic.Bool
+	// Se cierra al apagar para terminar las tareas de fondo, como la
+	// limpieza de los limitadores
+	stop     chan struct{}
+	stopOnce sync.Once
+	// Número de peticiones en curso, usado para informar del drenado al apagar
+	activeRequests atomic.Int64
+}
+
+// Crear el servidor con todas las rutas y middlewares sobre el store y la
+// configuración indicados, con los usuarios de ejemplo. main carga los datos
+// persistidos, abre el puerto y gestiona el apagado; los tests pueden usar
+// newServer(...).http.Handler con httptest.NewServer.
+func newServer(s Store, cfg Config) *server {
+	srv := &server{
+		config: cfg,
+		store:  s,
+		users: []User{
+			{ID: 1, Name: "Juan Pérez", Email: "juan@example.com", Role: roleAdmin, CreatedAt: startTime, UpdatedAt: startTime},
+			{ID: 2, Name: "María García", Email: "maria@example.com", Role: roleMember, CreatedAt: startTime, UpdatedAt: startTime},
+		},
+		nextID:        3,
+		flushRequests: make(chan struct{}, 1),
+		stop:          make(chan struct{}),
+		history:       &changeHistory{limit: cfg.HistoryLimit, entries: make(map[int][]historyEntry)},
+		usedNonces:    &nonceCache{ttl: cfg.NonceTTL, seen: make(map[string]time.Time)},
+		accessLog: &logBroadcaster{
+			subscribers: make(map[*logSubscriber]struct{}),
+			done:        make(chan struct{}),
+		},
+	}
+	srv.idGenerator = sequentialIDs{next: &srv.nextID}
+	// Solo un store persistente puede fallar al escribir
+	if _, inMemory := s.(memoryStore); !inMemory && cfg.BreakerThreshold > 0 {
+		srv.storeBreaker = newCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown)
+	}
+	srv.validators = srv.configuredValidators()
+	srv.readiness = srv.defaultHealthChecker()
+	srv.maintenance.Store(cfg.MaintenanceMode)
+	srv.http = &http.Server{
+		Addr:         ":" + cfg.Port,
+		Handler:      srv.activeRequestsMiddleware(srv.newRouter()),
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+	srv.http.RegisterOnShutdown(srv.accessLog.close)
+	srv.http.RegisterOnShutdown(func() { srv.stopOnce.Do(func() { close(srv.stop) }) })
+	return srv
+}
+
+// Crear el router con los middlewares y las rutas según config
+func (srv *server) newRouter() *mux.Router {
+	r := mux.NewRouter()
+
+	// Aplicar middlewares (orden en chain.go)
+	chain := srv.defaultChain()
+	chain.apply(r)
+	srv.debugf("Middleware chain: %s", strings.Join(chain.names(), " -> "))
+
+	// Definir rutas
+	r.HandleFunc("/health", srv.healthHandler).Methods("GET")
+	r.HandleFunc("/health/ready", srv.readinessHandler).Methods("GET")
+	r.HandleFunc("/api/users", srv.getUsersHandler).Methods("GET")
+	r.HandleFunc("/api/users", srv.headUsersHandler).Methods("HEAD")
+	r.HandleFunc("/api/users/batch", srv.getUsersBatchHandler).Methods("GET")
+	r.HandleFunc("/api/users/export", srv.exportUsersHandler).Methods("GET")
+	r.Handle(emailAvailablePath, srv.emailAvailableRoute()).Methods("GET")
+	r.HandleFunc("/api/users/me", srv.meHandler(srv.getUserHandler)).Methods("GET")
+	r.HandleFunc("/api/users/me", srv.meHandler(srv.updateUserHandler)).Methods("PUT")
+	r.HandleFunc("/api/users/me", srv.meHandler(srv.deleteUserHandler)).Methods("DELETE")
+	r.HandleFunc("/api/users/{id}", srv.getUserHandler).Methods("GET")
+	r.HandleFunc("/api/users", srv.createUserHandler).Methods("POST")
+	r.HandleFunc("/api/users/validate", srv.validateUserHandler).Methods("POST")
+	r.HandleFunc("/api/users/bulk", srv.requireAdmin(srv.bulkCreateUsersHandler)).Methods("POST")
+	r.HandleFunc("/api/users/bulk", srv.requireAdmin(srv.bulkUpdateUsersHandler)).Methods("PUT")
+	r.HandleFunc("/api/users/{id}", srv.updateUserHandler).Methods("PUT")
+	r.HandleFunc("/api/users/{id}/verify-email", srv.requireAdmin(srv.verifyEmailHandler)).Methods("POST")
+	r.HandleFunc("/api/users/{id}/tags", srv.userTagsHandler).Methods("POST", "DELETE")
+	r.HandleFunc("/api/users/{id}/history", srv.requireAdmin(srv.userHistoryHandler)).Methods("GET")
+	r.HandleFunc("/api/users/{id}", srv.jsonPatchUserHandler).Methods("PATCH").HeadersRegexp("Content-Type", `^application/json-patch\+json`)
+	r.HandleFunc("/api/users/{id}", srv.patchUserHandler).Methods("PATCH")
+	r.HandleFunc("/api/users/purge", srv.requireAdmin(srv.purgeUsersHandler)).Methods("DELETE")
+	r.HandleFunc("/api/users/{id}", srv.requireAdmin(srv.deleteUserHandler)).Methods("DELETE")
+	r.HandleFunc("/api/schema/user", srv.userSchemaHandler).Methods("GET")
+	r.HandleFunc("/admin/logs/stream", srv.requireAdmin(srv.logStreamHandler)).Methods("GET")
+	r.HandleFunc("/admin/maintenance", srv.requireAdmin(srv.maintenanceHandler)).Methods("GET", "PUT")
+	r.HandleFunc("/admin/integrity", srv.requireAdmin(srv.integrityHandler)).Methods("GET")
+	r.HandleFunc("/api/users", srv.optionsHandler(usersCollectionMethods)).Methods("OPTIONS")
+	r.HandleFunc("/api/users/{id}", srv.optionsHandler(userItemMethods)).Methods("OPTIONS")
+	r.HandleFunc("/api/users/{id}/history", srv.optionsHandler(userHistoryMethods)).Methods("OPTIONS")
+
+	return r
+}
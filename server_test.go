@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// El log de accesos de cada petición no aporta nada a la salida de go test
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// Servidor sobre httptest con la configuración por defecto, ajustada por
+// configure si no es nil. Se cierra al terminar el test.
+func newTestServer(t *testing.T, s Store, configure func(*Config)) (*server, *httptest.Server) {
+	t.Helper()
+	cfg := defaultConfig()
+	if configure != nil {
+		configure(&cfg)
+	}
+	if problems := cfg.validate(); len(problems) > 0 {
+		t.Fatalf("invalid test configuration: %v", errors.Join(problems...))
+	}
+	srv := newServer(s, cfg)
+	ts := httptest.NewServer(srv.http.Handler)
+	t.Cleanup(func() {
+		ts.Close()
+		srv.http.Shutdown(context.Background())
+	})
+	return srv, ts
+}
+
+// Hacer una petición al servidor de prueba; header son pares nombre, valor
+func doRequest(t *testing.T, ts *httptest.Server, method, path, body string, header ...string) (*http.Response, []byte) {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, ts.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s %s: reading body: %v", method, path, err)
+	}
+	return resp, data
+}
+
+// Decodificar una respuesta JSON del servicio
+func decodeResponse(t *testing.T, data []byte) Response {
+	t.Helper()
+	var response Response
+	if err := json.Unmarshal(data, &response); err != nil {
+		t.Fatalf("invalid JSON response %q: %v", data, err)
+	}
+	return response
+}
+
+// Crear un usuario y devolver su ID
+func createUser(t *testing.T, ts *httptest.Server, name, email string) int {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"name": name, "email": email})
+	resp, data := doRequest(t, ts, "POST", "/api/users", string(body))
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create %s: status %d: %s", email, resp.StatusCode, data)
+	}
+	var created struct {
+		Data User `json:"data"`
+	}
+	if err := json.Unmarshal(data, &created); err != nil {
+		t.Fatal(err)
+	}
+	return created.Data.ID
+}
+
+func TestHealthOverHTTPTest(t *testing.T) {
+	_, ts := newTestServer(t, memoryStore{}, nil)
+
+	resp, data := doRequest(t, ts, "GET", "/health", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /health: status %d: %s", resp.StatusCode, data)
+	}
+	if got := decodeResponse(t, data).Status; got != "success" {
+		t.Errorf("GET /health: status field %q, want success", got)
+	}
+}
+
+func TestServersDoNotShareState(t *testing.T) {
+	t.Parallel()
+	_, first := newTestServer(t, memoryStore{}, nil)
+	_, second := newTestServer(t, memoryStore{}, func(cfg *Config) { cfg.MaintenanceMode = true })
+
+	if id := createUser(t, first, "Ana Ruiz", "ana@example.com"); id != 3 {
+		t.Errorf("first user created got ID %d, want 3", id)
+	}
+
+	resp, data := doRequest(t, second, "GET", "/api/users", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /api/users on second server: status %d: %s", resp.StatusCode, data)
+	}
+	var list struct {
+		Data []User `json:"data"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Data) != 2 {
+		t.Errorf("second server has %d users, want the 2 seed users", len(list.Data))
+	}
+
+	// La configuración tampoco se comparte: solo el segundo está en mantenimiento
+	resp, _ = doRequest(t, second, "POST", "/api/users", `{"name":"Bea Ruiz","email":"bea@example.com"}`)
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("POST on server in maintenance: status %d, want 503", resp.StatusCode)
+	}
+	if id := createUser(t, first, "Bea Ruiz", "bea@example.com"); id != 4 {
+		t.Errorf("second user created on first server got ID %d, want 4", id)
+	}
+}
+
+func TestHealthDoesNotExposeAffectedUsers(t *testing.T) {
+	srv, ts := newTestServer(t, memoryStore{}, nil)
+	srv.users = append(srv.users, User{ID: 3, Name: "Juan P.", Email: "juan@example.com", Role: roleMember})
+	srv.checkDataIntegrity()
+
+	resp, data := doRequest(t, ts, "GET", "/health", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /health: status %d: %s", resp.StatusCode, data)
+	}
+	var health struct {
+		Data struct {
+			Integrity map[string]interface{} `json:"integrity"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &health); err != nil {
+		t.Fatal(err)
+	}
+	integrity := health.Data.Integrity
+	if integrity["duplicate_emails"] != float64(1) || integrity["status"] != "duplicate_emails" {
+		t.Errorf("integrity summary %v, want one duplicate email reported", integrity)
+	}
+	if len(integrity) != 2 || strings.Contains(string(data), "juan@example.com") {
+		t.Errorf("GET /health exposes more than the count and status: %s", data)
+	}
+
+	// El detalle sigue disponible para los admins
+	resp, data = doRequest(t, ts, "GET", "/admin/integrity", "")
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(data), "juan@example.com") {
+		t.Errorf("GET /admin/integrity: status %d, want the duplicate emails: %s", resp.StatusCode, data)
+	}
+}
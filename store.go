@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Estado persistido: los usuarios (incluidos tombstones) y el siguiente ID
+// a asignar, para que los IDs nunca se reutilicen tras reiniciar
+type storeData struct {
+	NextID int    `json:"next_id"`
+	Users  []User `json:"users"`
+}
+
+// Backend en el que se persisten los usuarios
+type Store interface {
+	// Nombre del backend para diagnósticos
+	Name() string
+	// Cargar el estado guardado; found es false si todavía no hay datos
+	Load() (loaded storeData, found bool, err error)
+	// Guardar el estado completo
+	Save(snapshot storeData) error
+	// Comprobar que el backend responde y sus datos son legibles
+	Ping(ctx context.Context) error
+}
+
+// Store que no persiste nada
+type memoryStore struct{}
+
+func (memoryStore) Name() string                   { return "memory" }
+func (memoryStore) Load() (storeData, bool, error) { return storeData{}, false, nil }
+func (memoryStore) Save(storeData) error           { return nil }
+func (memoryStore) Ping(ctx context.Context) error { return nil }
+
+// Store respaldado por un archivo JSON
+type fileStore struct {
+	path string
+
+	// Con watch, Save se niega a sobrescribir cambios externos que aún no se
+	// han recargado; stamp es la huella de la última lectura o escritura propia
+	watch bool
+	mu    sync.Mutex
+	stamp fileStamp
+}
+
+func (s *fileStore) Name() string { return "file" }
+
+// Cargar usuarios desde el archivo de datos si existe. También acepta el
+// formato antiguo, un array de usuarios sin next_id.
+func (s *fileStore) Load() (storeData, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stamp := fileStampOf(s.path)
+	loaded, found, err := s.read()
+	if err == nil {
+		s.stamp = stamp
+	}
+	return loaded, found, err
+}
+
+// Leer y decodificar el archivo sin actualizar la huella
+func (s *fileStore) read() (storeData, bool, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return storeData{}, false, nil
+	}
+	if err != nil {
+		return storeData{}, false, err
+	}
+
+	var loaded storeData
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(data, &loaded.Users)
+	} else {
+		err = json.Unmarshal(data, &loaded)
+	}
+	if err != nil {
+		return storeData{}, false, err
+	}
+	return loaded, true, nil
+}
+
+// Guardar usuarios en el archivo de datos de forma atómica
+func (s *fileStore) Save(snapshot storeData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.watch && fileStampOf(s.path) != s.stamp {
+		return errExternalChange
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	s.stamp = fileStampOf(s.path)
+	return nil
+}
+
+// Leer y decodificar el archivo para detectar que está corrupto o inaccesible
+func (s *fileStore) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, _, err := s.read()
+	return err
+}
+
+// Crear el directorio del archivo de datos si no existe, solo accesible
+// para el usuario y su grupo; created indica si hubo que crearlo
+func (s *fileStore) ensureDir() (created bool, err error) {
+	dir := filepath.Dir(s.path)
+	if _, err := os.Stat(dir); err == nil {
+		return false, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Comprobar que se puede escribir junto al archivo de datos
+func (s *fileStore) checkWritable() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".probe-*")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	return os.Remove(tmp.Name())
+}
+
+// Cargar en memoria los usuarios del store activo
+func (srv *server) loadUsers() error {
+	var loaded storeData
+	var found bool
+	err := srv.withRetry("load", srv.config.StoreRetryAttempts, 0, func() (err error) {
+		loaded, found, err = srv.store.Load()
+		return err
+	})
+	if err != nil || !found {
+		return err
+	}
+
+	srv.users = loaded.Users
+	srv.nextID = persistedNextID(loaded.NextID, srv.users)
+	return nil
+}
+
+// Siguiente ID a persistir: nunca menor que el de ningún usuario conocido,
+// incluidos los eliminados
+func persistedNextID(current int, list []User) int {
+	next := max(current, 1)
+	for _, user := range list {
+		if user.ID >= next {
+			next = user.ID + 1
+		}
+	}
+	return next
+}
+
+// Estado a guardar a partir de una lista de usuarios.
+// Debe llamarse con usersMu bloqueado.
+func (srv *server) snapshotFor(list []User) storeData {
+	return storeData{NextID: persistedNextID(srv.nextID, list), Users: list}
+}
+
+// Marcar el servicio como degradado tras un fallo de escritura
+func (srv *server) markDegraded(err error) {
+	if !srv.degraded.Swap(true) {
+		log.Printf("ERROR: data file %s is not writable, rejecting mutations until it is: %v", srv.config.DataFile, err)
+	}
+}
+
+// Volver a aceptar escrituras tras una escritura correcta
+func (srv *server) clearDegraded() {
+	if srv.degraded.Swap(false) {
+		log.Printf("Data file %s is writable again", srv.config.DataFile)
+	}
+}
+
+// En modo async un servicio degradado rechaza los cambios, así que no queda
+// nada que guardar que demuestre que el archivo se ha recuperado: se
+// comprueba directamente
+func (srv *server) probeWritable() {
+	fs, ok := srv.store.(*fileStore)
+	if !ok || !srv.degraded.Load() {
+		return
+	}
+	if err := fs.checkWritable(); err == nil {
+		srv.clearDegraded()
+	}
+}
+
+// Avisar al flusher sin bloquear
+func (srv *server) requestFlush() {
+	select {
+	case srv.flushRequests <- struct{}{}:
+	default:
+	}
+}
+
+// Persistir el nuevo estado y aplicarlo en memoria solo si se guardó.
+// En modo async el estado se aplica de inmediato y se escribe en el
+// siguiente flush. Debe llamarse con usersMu bloqueado para escritura.
+func (srv *server) commitUsers(w http.ResponseWriter, r *http.Request, next []User) bool {
+	if srv.config.PersistMode == "async" {
+		if srv.degraded.Load() {
+			srv.writeError(w, r, http.StatusServiceUnavailable, codeStoreUnavailable, "Data store is read-only, changes were not saved")
+			return false
+		}
+		srv.recordHistory(r, srv.users, next)
+		srv.users = next
+		srv.dirty = true
+		srv.dataVersion.Add(1)
+		srv.requestFlush()
+		return true
+	}
+
+	if err := srv.saveSnapshot(srv.snapshotFor(next), lockedRetryBudget); err != nil {
+		if errors.Is(err, errCircuitOpen) {
+			srv.writeCircuitOpen(w, r)
+			return false
+		}
+		if errors.Is(err, errExternalChange) {
+			setRetryAfter(w, srv.config.WatchInterval)
+			srv.writeError(w, r, http.StatusServiceUnavailable, codeStoreUnavailable, "Data file was modified externally and is being reloaded, changes were not saved")
+			return false
+		}
+		srv.markDegraded(err)
+		srv.writeError(w, r, http.StatusServiceUnavailable, codeStoreUnavailable, "Data store is read-only, changes were not saved")
+		return false
+	}
+	srv.clearDegraded()
+
+	srv.recordHistory(r, srv.users, next)
+	srv.users = next
+	srv.dataVersion.Add(1)
+	return true
+}
+
+// Escribir en disco los cambios pendientes (modo async); devuelve false si
+// la escritura falló y los cambios siguen pendientes
+func (srv *server) flushUsers() bool {
+	// Sin cruzarse con una recarga entre la copia y la escritura
+	srv.persistMu.Lock()
+	defer srv.persistMu.Unlock()
+
+	srv.usersMu.Lock()
+	if !srv.dirty {
+		srv.usersMu.Unlock()
+		return true
+	}
+	snapshot := srv.snapshotFor(slices.Clone(srv.users))
+	srv.dirty = false
+	srv.usersMu.Unlock()
+
+	if err := srv.saveSnapshot(snapshot, 0); err != nil {
+		if !errors.Is(err, errCircuitOpen) && !errors.Is(err, errExternalChange) {
+			srv.markDegraded(err)
+		}
+		srv.usersMu.Lock()
+		srv.dirty = true
+		srv.usersMu.Unlock()
+		return false
+	}
+	srv.clearDegraded()
+	return true
+}
+
+// Agrupar las escrituras: cada ráfaga de cambios produce como mucho una
+// escritura por intervalo, siempre con el estado más reciente. Un cambio
+// tras un periodo sin escrituras se guarda de inmediato; al cerrarse stop se
+// hace un último flush. Mientras el servicio esté degradado se comprueba
+// cada intervalo si el archivo vuelve a admitir escrituras.
+func (srv *server) runFlusher(interval time.Duration, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	probe := time.NewTicker(interval)
+	defer probe.Stop()
+
+	var lastWrite time.Time
+	var timer *time.Timer
+	var scheduled <-chan time.Time
+
+	flush := func() {
+		scheduled = nil
+		lastWrite = time.Now()
+		if !srv.flushUsers() {
+			// Reintentar en el siguiente intervalo aunque no lleguen más cambios
+			timer = time.NewTimer(interval)
+			scheduled = timer.C
+		}
+	}
+
+	for {
+		select {
+		case <-srv.flushRequests:
+			if scheduled != nil {
+				continue
+			}
+			wait := interval - time.Since(lastWrite)
+			if wait <= 0 {
+				flush()
+				continue
+			}
+			timer = time.NewTimer(wait)
+			scheduled = timer.C
+		case <-scheduled:
+			flush()
+		case <-probe.C:
+			// Con un flush pendiente es el propio flush el que reintenta
+			if scheduled == nil {
+				srv.probeWritable()
+			}
+		case <-stop:
+			if timer != nil {
+				timer.Stop()
+			}
+			srv.flushUsers()
+			return
+		}
+	}
+}
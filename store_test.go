@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIDsAreNotReusedAfterReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+
+	_, ts := newTestServer(t, &fileStore{path: path}, nil)
+	id := createUser(t, ts, "Ana Ruiz", "ana@example.com")
+	if resp, data := doRequest(t, ts, "DELETE", "/api/users/"+strconv.Itoa(id), ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("DELETE: status %d: %s", resp.StatusCode, data)
+	}
+	// Sin el tombstone, solo next_id evita reutilizar el ID
+	if resp, data := doRequest(t, ts, "DELETE", "/api/users/purge", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("purge: status %d: %s", resp.StatusCode, data)
+	}
+
+	// Reinicio: un servidor nuevo que carga el mismo archivo
+	restarted, ts := newTestServer(t, &fileStore{path: path}, nil)
+	if err := restarted.loadUsers(); err != nil {
+		t.Fatalf("loadUsers: %v", err)
+	}
+	if next := createUser(t, ts, "Bea Ruiz", "bea@example.com"); next <= id {
+		t.Errorf("user created after reload got ID %d, want greater than the deleted ID %d", next, id)
+	}
+}
+
+func TestDataFileInNestedMissingDirectories(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "a", "b", "c", "users.json")
+	store := &fileStore{path: path}
+
+	// Mismo arranque que main con DATA_FILE
+	created, err := store.ensureDir()
+	if err != nil || !created {
+		t.Fatalf("ensureDir = %v, %v; want the directories created", created, err)
+	}
+	for dir := filepath.Dir(path); dir != root; dir = filepath.Dir(dir) {
+		info, err := os.Stat(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := info.Mode().Perm(); perm&^0o750 != 0 {
+			t.Errorf("%s has permissions %o, want at most 750", dir, perm)
+		}
+	}
+	if created, err := store.ensureDir(); err != nil || created {
+		t.Errorf("second ensureDir = %v, %v; want the existing directory reused", created, err)
+	}
+	if err := store.checkWritable(); err != nil {
+		t.Fatalf("checkWritable: %v", err)
+	}
+
+	_, ts := newTestServer(t, store, nil)
+	createUser(t, ts, "Ana Ruiz", "ana@example.com")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("data file was not written: %v", err)
+	}
+	if !strings.Contains(string(data), "ana@example.com") {
+		t.Errorf("data file does not contain the created user: %s", data)
+	}
+}
+
+func TestAsyncWritesResumeWhenDataFileRecovers(t *testing.T) {
+	// Directorio que aún no existe: el archivo no admite escrituras
+	dir := filepath.Join(t.TempDir(), "data")
+	store := &fileStore{path: filepath.Join(dir, "users.json")}
+	srv, ts := newTestServer(t, store, func(cfg *Config) {
+		cfg.PersistMode = "async"
+		cfg.FlushInterval = 10 * time.Millisecond
+	})
+	stop, done := make(chan struct{}), make(chan struct{})
+	go srv.runFlusher(srv.config.FlushInterval, stop, done)
+	t.Cleanup(func() {
+		close(stop)
+		<-done
+	})
+
+	// Mismo arranque que main
+	if err := store.checkWritable(); err == nil {
+		t.Fatal("checkWritable succeeded without the data directory")
+	} else {
+		srv.markDegraded(err)
+	}
+	resp, data := doRequest(t, ts, "POST", "/api/users", `{"name":"Ana Ruiz","email":"ana@example.com"}`)
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("POST while degraded: status %d, want 503: %s", resp.StatusCode, data)
+	}
+
+	if err := os.Mkdir(dir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(2 * time.Second); srv.degraded.Load(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("server is still degraded after the data directory became writable")
+		}
+	}
+	createUser(t, ts, "Ana Ruiz", "ana@example.com")
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		if data, err := os.ReadFile(store.path); err == nil && strings.Contains(string(data), "ana@example.com") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the change made after recovering was never flushed to the data file")
+		}
+	}
+}
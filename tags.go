@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Límites de las etiquetas de un usuario
+const (
+	maxTags      = 20
+	maxTagLength = 32
+)
+
+// Formato de una etiqueta ya normalizada: sin espacios, empieza por letra o
+// dígito
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.:-]*$`)
+
+var (
+	tooManyTagsMessage = fmt.Sprintf("At most %d tags are allowed", maxTags)
+	invalidTagMessage  = fmt.Sprintf("Tags must be 1 to %d lowercase letters, digits, '-', '_', '.' or ':' with no spaces", maxTagLength)
+)
+
+// Pasar las etiquetas a minúsculas y quitar las repetidas conservando el
+// orden; siempre devuelve un slice nuevo
+func normalizeTags(tags []string) []string {
+	var out []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !slices.Contains(out, tag) {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// Validar las etiquetas ya normalizadas
+func validateTags(tags []string) *fieldError {
+	if len(tags) > maxTags {
+		return &fieldError{"tags", fieldTooLong, tooManyTagsMessage}
+	}
+	for _, tag := range tags {
+		if len(tag) > maxTagLength || !tagPattern.MatchString(tag) {
+			return &fieldError{"tags", fieldInvalidFormat, invalidTagMessage}
+		}
+	}
+	return nil
+}
+
+// Filtro ?tag=a&tag=b (o ?tag=a,b); con ?tag_match=any basta una de ellas
+type tagFilter struct {
+	tags []string
+	any  bool
+}
+
+func (f tagFilter) match(user User) bool {
+	if len(f.tags) == 0 {
+		return true
+	}
+	if f.any {
+		return slices.ContainsFunc(f.tags, func(tag string) bool { return slices.Contains(user.Tags, tag) })
+	}
+	return !slices.ContainsFunc(f.tags, func(tag string) bool { return !slices.Contains(user.Tags, tag) })
+}
+
+// Añadir (POST) o quitar (DELETE) etiquetas de un usuario con un cuerpo
+// {"tags": [...]}; responde con el usuario actualizado
+func (srv *server) userTagsHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := srv.parseUserID(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Tags []string `json:"tags"`
+	}
+	if !srv.decodeJSONBody(w, r, &body) {
+		return
+	}
+	tags := normalizeTags(body.Tags)
+	if len(tags) == 0 {
+		srv.writeError(w, r, http.StatusUnprocessableEntity, codeValidationFailed, "At least one tag is required")
+		return
+	}
+	if err := validateTags(tags); err != nil && r.Method == "POST" {
+		srv.writeValidationErrors(w, r, []fieldError{*err})
+		return
+	}
+
+	srv.usersMu.Lock()
+	defer srv.usersMu.Unlock()
+
+	i := indexOfUser(srv.users, id)
+	if i < 0 {
+		srv.writeError(w, r, http.StatusNotFound, codeNotFound, "User not found")
+		return
+	}
+
+	var updated []string
+	if r.Method == "POST" {
+		updated = normalizeTags(append(slices.Clone(srv.users[i].Tags), tags...))
+	} else {
+		updated = slices.DeleteFunc(slices.Clone(srv.users[i].Tags), func(tag string) bool { return slices.Contains(tags, tag) })
+	}
+	if err := validateTags(updated); err != nil {
+		srv.writeValidationErrors(w, r, []fieldError{*err})
+		return
+	}
+
+	next := slices.Clone(srv.users)
+	if !slices.Equal(updated, next[i].Tags) {
+		if len(updated) == 0 {
+			updated = nil
+		}
+		next[i].Tags = updated
+		next[i].UpdatedAt = time.Now().UTC()
+		if !srv.commitUsers(w, r, next) {
+			return
+		}
+	}
+	srv.writeJSON(w, http.StatusOK, Response{
+		Status:  "success",
+		Message: "User tags updated",
+		Data:    srv.userData(r, next[i]),
+	})
+}
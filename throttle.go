@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// El cliente envía el cuerpo por debajo del ritmo mínimo configurado
+var errBodyTooSlow = errors.New("request body arrived too slowly")
+
+// Lector del cuerpo que exige un ritmo medio mínimo de bytes por segundo.
+// Antes de cada lectura fija el plazo de lectura de la conexión al instante
+// en que el ritmo medio caería por debajo del mínimo, para que un cliente
+// que deja de enviar no bloquee la lectura indefinidamente.
+type minRateReader struct {
+	body  io.ReadCloser
+	rc    *http.ResponseController
+	rate  float64
+	grace time.Duration
+	start time.Time
+	read  int64
+	done  bool
+}
+
+func (m *minRateReader) Read(p []byte) (int, error) {
+	if m.done {
+		return m.body.Read(p)
+	}
+
+	allowed := m.grace + time.Duration(float64(m.read+1)/m.rate*float64(time.Second))
+	m.rc.SetReadDeadline(m.start.Add(allowed))
+
+	n, err := m.body.Read(p)
+	m.read += int64(n)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		// Se deja vencido el plazo para que el servidor no espere al
+		// resto del cuerpo al cerrar la petición
+		m.done = true
+		return n, errBodyTooSlow
+	}
+	if err != nil {
+		m.finish()
+	}
+	return n, err
+}
+
+func (m *minRateReader) Close() error {
+	m.finish()
+	return m.body.Close()
+}
+
+// Quitar el plazo de lectura una vez leído el cuerpo
+func (m *minRateReader) finish() {
+	if !m.done {
+		m.done = true
+		m.rc.SetReadDeadline(time.Time{})
+	}
+}
+
+// Middleware que aborta con 408 los cuerpos que llegan demasiado despacio
+func (srv *server) minBodyRateMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		if err := rc.SetReadDeadline(time.Time{}); err != nil {
+			// La conexión no permite plazos de lectura (p. ej. en tests)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r.Body = &minRateReader{
+			body:  r.Body,
+			rc:    rc,
+			rate:  float64(srv.config.MinBodyReadRate),
+			grace: srv.config.BodyReadGrace,
+			start: time.Now(),
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Middleware que limita el tamaño del cuerpo a MAX_BODY_BYTES. Si
+// Content-Length ya lo supera se responde 413 sin leer nada: con Expect:
+// 100-continue net/http solo envía el 100 Continue cuando el handler lee el
+// cuerpo, así que el cliente recibe directamente el 413 en lugar de quedarse
+// esperando o subir el cuerpo entero. Sin Content-Length (chunked) el límite
+// lo aplica MaxBytesReader al leer. En ambos casos la espera del cliente
+// sigue acotada por READ_TIMEOUT y, si está activo, MIN_BODY_READ_RATE.
+func (srv *server) maxBodyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := srv.bodyLimit(r)
+		if r.ContentLength > limit {
+			// El servidor cierra la conexión en lugar de descartar el cuerpo
+			w.Header().Set("Connection", "close")
+			srv.writeError(w, r, http.StatusRequestEntityTooLarge, codePayloadTooLarge, "Request body must not exceed %d bytes", limit)
+			return
+		}
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Tamaño máximo del cuerpo de la petición en bytes (también descomprimido)
+func (srv *server) bodyLimit(r *http.Request) int64 {
+	if streamsBody(r) {
+		return srv.config.BulkMaxBodyBytes
+	}
+	return srv.config.MaxBodyBytes
+}
+
+// Responder a un error al leer el cuerpo de la petición
+func (srv *server) writeBodyReadError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		srv.writeError(w, r, http.StatusRequestEntityTooLarge, codePayloadTooLarge, "Request body must not exceed %d bytes", tooLarge.Limit)
+		return
+	}
+	if errors.Is(err, errInvalidGzip) {
+		srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "Request body is not valid gzip")
+		return
+	}
+	if errors.Is(err, errInvalidUTF8) {
+		srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "Request body must be valid UTF-8")
+		return
+	}
+	if errors.Is(err, errBodyTooSlow) {
+		srv.writeError(w, r, http.StatusRequestTimeout, codeRequestTimeout, "Request body was sent too slowly")
+		return
+	}
+	srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "Could not read request body")
+}
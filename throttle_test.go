@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Abrir una conexión TCP al servidor de prueba que no puede colgar el test
+func dialTestServer(t *testing.T, addr string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return conn, bufio.NewReader(conn)
+}
+
+func TestExpectContinueWithDeclaredOversizedBody(t *testing.T) {
+	_, ts := newTestServer(t, memoryStore{}, func(cfg *Config) { cfg.MaxBodyBytes = 1024 })
+	conn, reader := dialTestServer(t, ts.Listener.Addr().String())
+
+	// El cliente espera el 100 Continue antes de enviar el cuerpo
+	fmt.Fprintf(conn, "POST /api/users HTTP/1.1\r\nHost: test\r\nContent-Type: application/json\r\nContent-Length: %d\r\nExpect: 100-continue\r\n\r\n", 1<<20)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("no response while waiting for 100 Continue: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("status %d, want 413 without 100 Continue", resp.StatusCode)
+	}
+}
+
+func TestExpectContinueWithChunkedOversizedBody(t *testing.T) {
+	_, ts := newTestServer(t, memoryStore{}, func(cfg *Config) { cfg.MaxBodyBytes = 1024 })
+	conn, reader := dialTestServer(t, ts.Listener.Addr().String())
+
+	fmt.Fprint(conn, "POST /api/users HTTP/1.1\r\nHost: test\r\nContent-Type: application/json\r\nTransfer-Encoding: chunked\r\nExpect: 100-continue\r\n\r\n")
+	// Sin tamaño declarado el handler empieza a leer y el servidor da paso
+	interim, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("no interim response: %v", err)
+	}
+	if interim.StatusCode != http.StatusContinue {
+		t.Fatalf("interim status %d, want 100", interim.StatusCode)
+	}
+
+	chunk := `{"name":"` + strings.Repeat("a", 4096) + `"}`
+	fmt.Fprintf(conn, "%x\r\n%s\r\n0\r\n\r\n", len(chunk), chunk)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("no final response: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("status %d, want 413", resp.StatusCode)
+	}
+}
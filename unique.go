@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Código por campo de un valor que ya usa otro usuario
+const fieldDuplicate = "duplicate"
+
+// Campos de User que se pueden declarar únicos. Los valores se comparan sin
+// distinguir mayúsculas y un valor vacío nunca choca.
+var uniqueUserFields = map[string]func(*User) *string{
+	"name":  func(u *User) *string { return &u.Name },
+	"email": func(u *User) *string { return &u.Email },
+}
+
+// Clave de unicidad de un campo de un usuario
+func uniqueKey(field string, user User) string {
+	return strings.ToLower(*uniqueUserFields[field](&user))
+}
+
+// Valor de un campo único tal como lo envió el cliente
+func uniqueValue(field string, user User) string {
+	return *uniqueUserFields[field](&user)
+}
+
+// Primer campo único configurado en el que user choca con otro usuario no
+// eliminado de la lista, ignorando el usuario exceptID, o ""
+func (srv *server) findUniqueConflict(list []User, user User, exceptID int) string {
+	for _, field := range srv.config.UniqueFields {
+		key := uniqueKey(field, user)
+		if key == "" {
+			continue
+		}
+		for _, other := range list {
+			if other.ID != exceptID && other.DeletedAt == nil && uniqueKey(field, other) == key {
+				return field
+			}
+		}
+	}
+	return ""
+}
+
+// Claves únicas ya vistas dentro de un lote, por campo
+type uniqueIndex map[string]map[string]bool
+
+// Registrar las claves de user en los campos fields; devuelve el campo que ya
+// estaba en el índice (sin registrar nada) o ""
+func (idx uniqueIndex) add(fields []string, user User) string {
+	for _, field := range fields {
+		if key := uniqueKey(field, user); key != "" && idx[field][key] {
+			return field
+		}
+	}
+	for _, field := range fields {
+		if idx[field] == nil {
+			idx[field] = make(map[string]bool)
+		}
+		if key := uniqueKey(field, user); key != "" {
+			idx[field][key] = true
+		}
+	}
+	return ""
+}
+
+// Responder 409 indicando qué campo único chocó
+func (srv *server) writeUniqueConflict(w http.ResponseWriter, r *http.Request, field string, user User) {
+	w.Header().Set("Content-Language", requestLanguage(r))
+	w.Header().Add("Vary", "Accept-Language")
+	message := localize(r, "A user with %s %q already exists", field, uniqueValue(field, user))
+	srv.writeJSON(w, http.StatusConflict, Response{
+		Status:  "error",
+		Message: message,
+		Code:    codeConflict,
+		Errors:  []fieldError{{Field: field, Code: fieldDuplicate, Message: message}},
+	})
+}
+
+// Error de un elemento de un lote que choca en un campo único
+func uniqueConflictItem(index int, field string, user User) *apiError {
+	return newAPIError(http.StatusConflict, codeConflict, "Record %d: a user with %s %q already exists", index, field, uniqueValue(field, user))
+}
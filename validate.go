@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Validar un usuario sin crearlo: normaliza, aplica los valores por defecto y
+// pasa los validadores igual que una creación, pero nunca toca el store ni
+// usersMu, así que no comprueba que el email esté libre (para eso está
+// /api/users/email-available). Con ?fields=name,email solo se informan los
+// errores de esos campos, para validar un formulario campo a campo.
+func (srv *server) validateUserHandler(w http.ResponseWriter, r *http.Request) {
+	var fields []string
+	if v := r.URL.Query().Get("fields"); v != "" {
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if _, ok := normalizableFields[field]; !ok && field != "tags" {
+				srv.writeError(w, r, http.StatusBadRequest, codeBadRequest, "Invalid fields: unknown field %q", field)
+				return
+			}
+			fields = append(fields, field)
+		}
+	}
+
+	var user User
+	if !srv.decodeJSONBody(w, r, &user) {
+		return
+	}
+	srv.normalizeUser(&user)
+	srv.applyUserDefaults(&user)
+
+	errs := srv.validateUserFields(user)
+	if fields != nil {
+		errs = slices.DeleteFunc(errs, func(e fieldError) bool {
+			return !slices.Contains(fields, e.Field)
+		})
+	}
+	if len(errs) > 0 {
+		srv.writeValidationErrors(w, r, errs)
+		return
+	}
+
+	tags := user.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	srv.writeJSON(w, http.StatusOK, Response{
+		Status:  "success",
+		Message: "User is valid",
+		// Solo los campos que el cliente envía; ID y fechas los asigna la creación
+		Data: map[string]interface{}{
+			"name":  user.Name,
+			"email": user.Email,
+			"role":  user.Role,
+			"tags":  tags,
+		},
+	})
+}
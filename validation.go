@@ -0,0 +1,157 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"net/mail"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Formatos de los errores de validación (VALIDATION_ERRORS). El cliente
+// puede elegir uno con el parámetro errors del tipo de medio, p. ej.
+// Accept: application/json; errors=flat
+const (
+	validationStructured = "structured"
+	validationFlat       = "flat"
+)
+
+// Códigos por campo del formato structured
+const (
+	fieldRequired      = "required"
+	fieldTooLong       = "too_long"
+	fieldInvalidFormat = "invalid_format"
+	fieldInvalidValue  = "invalid_value"
+)
+
+// Error de validación de un campo concreto
+type fieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Regla de validación de usuarios que se aplica al crear o modificar;
+// devuelve un error por cada campo que no la cumple
+type Validator interface {
+	Validate(user User) []fieldError
+}
+
+// Reglas según config: las de siempre y, con ALLOWED_EMAIL_DOMAINS, la
+// lista de dominios admitidos
+func (srv *server) configuredValidators() []Validator {
+	list := []Validator{defaultValidator{}}
+	if len(srv.config.AllowedEmailDomains) > 0 {
+		list = append(list, emailDomainValidator{domains: srv.config.AllowedEmailDomains})
+	}
+	return list
+}
+
+// Comprobar un usuario con todas las reglas. Cada campo lleva como mucho un
+// error, el de la primera regla que falla.
+func (srv *server) validateUserFields(user User) []fieldError {
+	var errs []fieldError
+	for _, v := range srv.validators {
+		for _, e := range v.Validate(user) {
+			if !slices.ContainsFunc(errs, func(prev fieldError) bool { return prev.Field == e.Field }) {
+				errs = append(errs, e)
+			}
+		}
+	}
+	return errs
+}
+
+// Reglas básicas: campos obligatorios, longitudes, formato del email, rol y
+// etiquetas
+type defaultValidator struct{}
+
+func (defaultValidator) Validate(user User) []fieldError {
+	var errs []fieldError
+	if user.Name == "" {
+		errs = append(errs, fieldError{"name", fieldRequired, "Name is required"})
+	} else if utf8.RuneCountInString(user.Name) > maxNameLength {
+		errs = append(errs, fieldError{"name", fieldTooLong, nameTooLongMessage})
+	}
+
+	if user.Email == "" {
+		errs = append(errs, fieldError{"email", fieldRequired, "Email is required"})
+	} else if utf8.RuneCountInString(user.Email) > maxEmailLength {
+		errs = append(errs, fieldError{"email", fieldTooLong, emailTooLongMessage})
+	} else if addr, err := mail.ParseAddress(user.Email); err != nil || addr.Address != user.Email {
+		errs = append(errs, fieldError{"email", fieldInvalidFormat, "Invalid email format"})
+	}
+
+	if user.Role != "" && !slices.Contains(allowedRoles, user.Role) {
+		errs = append(errs, fieldError{"role", fieldInvalidValue, "Role must be one of: " + strings.Join(allowedRoles, ", ")})
+	}
+	if err := validateTags(user.Tags); err != nil {
+		errs = append(errs, *err)
+	}
+	return errs
+}
+
+// Solo admite emails de los dominios indicados (en minúsculas)
+type emailDomainValidator struct {
+	domains []string
+}
+
+func (v emailDomainValidator) Validate(user User) []fieldError {
+	at := strings.LastIndex(user.Email, "@")
+	if at < 0 {
+		// Sin dominio ya falla el formato
+		return nil
+	}
+	if !slices.Contains(v.domains, strings.ToLower(user.Email[at+1:])) {
+		return []fieldError{{"email", fieldInvalidValue, "Email domain is not allowed"}}
+	}
+	return nil
+}
+
+// Mensaje único del formato flat: el primer error, con los campos
+// obligatorios siempre por delante como hasta ahora
+func flatValidationMessage(errs []fieldError) string {
+	if len(errs) == 0 {
+		return ""
+	}
+	for _, e := range errs {
+		if e.Code == fieldRequired {
+			return "Name and email are required"
+		}
+	}
+	return errs[0].Message
+}
+
+// Formato de errores de validación pedido por el cliente o el configurado
+func (srv *server) validationErrorFormat(r *http.Request) string {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if _, params, err := mime.ParseMediaType(accept); err == nil {
+			if format := params["errors"]; format == validationStructured || format == validationFlat {
+				return format
+			}
+		}
+	}
+	return srv.config.ValidationErrors
+}
+
+// Responder 422 con los errores de validación en el formato elegido. En
+// ambos formatos message contiene el mensaje flat; structured añade además
+// la lista errors con un elemento por campo.
+func (srv *server) writeValidationErrors(w http.ResponseWriter, r *http.Request, errs []fieldError) {
+	w.Header().Set("Content-Language", requestLanguage(r))
+	w.Header().Add("Vary", "Accept-Language")
+	w.Header().Add("Vary", "Accept")
+
+	resp := Response{
+		Status:  "error",
+		Message: localize(r, flatValidationMessage(errs)),
+		Code:    codeValidationFailed,
+	}
+	if srv.validationErrorFormat(r) == validationStructured {
+		for _, e := range errs {
+			e.Message = localize(r, e.Message)
+			resp.Errors = append(resp.Errors, e)
+		}
+	}
+	srv.writeJSON(w, http.StatusUnprocessableEntity, resp)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Tipo MIME de vCard (RFC 6350)
+const vcardMediaType = "text/vcard"
+
+// Escapar un valor de texto de vCard (RFC 6350 §3.4)
+var vcardEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// Añadir una línea de contenido plegándola a 75 octetos (RFC 6350 §3.2) sin
+// partir ninguna secuencia UTF-8
+func appendVCardLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// La continuación empieza con un espacio que también cuenta
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+// Representación vCard 4.0 de un usuario. El modelo no tiene teléfono ni
+// dirección, así que no hay líneas TEL ni ADR; los campos vacíos se omiten.
+func marshalUserVCard(user User) string {
+	var b strings.Builder
+	appendVCardLine(&b, "BEGIN:VCARD")
+	appendVCardLine(&b, "VERSION:4.0")
+	appendVCardLine(&b, "KIND:individual")
+	appendVCardLine(&b, "UID:urn:x-user-id:"+strconv.Itoa(user.ID))
+	// FN es obligatorio en vCard 4.0
+	appendVCardLine(&b, "FN:"+vcardEscaper.Replace(user.Name))
+	if user.Email != "" {
+		appendVCardLine(&b, "EMAIL;TYPE=work:"+vcardEscaper.Replace(user.Email))
+	}
+	if len(user.Tags) > 0 {
+		// Las etiquetas no llevan comas, que separan las categorías
+		appendVCardLine(&b, "CATEGORIES:"+strings.Join(user.Tags, ","))
+	}
+	if !user.UpdatedAt.IsZero() {
+		appendVCardLine(&b, "REV:"+user.UpdatedAt.UTC().Format("20060102T150405Z"))
+	}
+	appendVCardLine(&b, "END:VCARD")
+	return b.String()
+}
+
+// Responder con la vCard de un usuario como archivo user-<id>.vcf
+func writeVCard(w http.ResponseWriter, status int, user User) {
+	w.Header().Set("Content-Type", vcardMediaType+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%d.vcf"`, user.ID))
+	w.WriteHeader(status)
+	w.Write([]byte(marshalUserVCard(user)))
+}
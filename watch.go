@@ -0,0 +1,140 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"log"
+	"os"
+	"slices"
+	"time"
+)
+
+// Políticas al recargar un archivo de datos modificado externamente
+// (RELOAD_POLICY). Con replace el archivo manda y se descartan los cambios
+// en memoria aún no guardados; con merge se unen ambos y, para un mismo ID,
+// gana la versión con updated_at más reciente.
+const (
+	reloadReplace = "replace"
+	reloadMerge   = "merge"
+)
+
+// Save se negó a escribir porque el archivo cambió y no se ha recargado
+var errExternalChange = errors.New("data file was modified externally")
+
+// Huella del archivo de datos para distinguir las escrituras propias de las
+// externas. Incluye un hash del contenido porque un editor puede conservar
+// la fecha y el tamaño (p. ej. al cambiar un carácter desde el mismo
+// segundo), y entonces Save sobrescribiría la edición.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	sum     [sha256.Size]byte
+}
+
+// Huella actual del archivo; vacía si no existe. Lee el archivo completo,
+// lo que a la escala de DATA_FILE es barato incluso cada WATCH_INTERVAL.
+func fileStampOf(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	stamp := fileStamp{modTime: info.ModTime(), size: info.Size()}
+	if data, err := os.ReadFile(path); err == nil {
+		stamp.sum = sha256.Sum256(data)
+	}
+	return stamp
+}
+
+// El archivo cambió desde la última lectura o escritura propia
+func (s *fileStore) changedExternally() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fileStampOf(s.path) != s.stamp
+}
+
+// Comprobar el archivo cada interval y recargarlo si lo modificó otro
+// proceso; se detiene al cerrarse stop
+func (srv *server) runFileWatcher(s *fileStore, interval time.Duration, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if s.changedExternally() {
+				srv.reloadUsers(s)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Recargar el archivo de datos aplicando RELOAD_POLICY
+func (srv *server) reloadUsers(s *fileStore) {
+	srv.persistMu.Lock()
+	defer srv.persistMu.Unlock()
+	srv.usersMu.Lock()
+	defer srv.usersMu.Unlock()
+
+	loaded, found, err := s.Load()
+	if err != nil {
+		// Puede estar a medio escribir; se reintenta en la siguiente comprobación
+		log.Printf("WARN: data file %s changed but could not be reloaded: %v", s.path, err)
+		return
+	}
+	if !found {
+		log.Printf("WARN: data file %s was removed, keeping the in-memory data", s.path)
+		return
+	}
+
+	pending := false
+	switch srv.config.ReloadPolicy {
+	case reloadMerge:
+		srv.users, pending = mergeUsers(srv.users, loaded.Users)
+	default:
+		if srv.dirty {
+			log.Printf("WARN: discarding unsaved changes replaced by the reloaded data file %s", s.path)
+		}
+		srv.users = loaded.Users
+	}
+	srv.nextID = persistedNextID(max(srv.nextID, loaded.NextID), srv.users)
+	srv.dirty = false
+	srv.dataVersion.Add(1)
+
+	srv.duplicateEmails = findDuplicateEmails(srv.users)
+	for _, dup := range srv.duplicateEmails {
+		log.Printf("WARN: email %s is shared by users %v", dup.Email, dup.IDs)
+	}
+	log.Printf("Reloaded %d users from %s (%s policy)", len(srv.users), s.path, srv.config.ReloadPolicy)
+
+	// Guardar lo que la fusión añadió al archivo
+	if pending {
+		if srv.config.PersistMode == "async" {
+			srv.dirty = true
+			srv.requestFlush()
+		} else if err := srv.saveSnapshot(srv.snapshotFor(srv.users), lockedRetryBudget); err != nil {
+			srv.markDegraded(err)
+		}
+	}
+}
+
+// Unir los usuarios en memoria con los del archivo. Devuelve si el
+// resultado tiene cambios que el archivo no tiene.
+func mergeUsers(memory, file []User) ([]User, bool) {
+	merged := slices.Clone(file)
+	pending := false
+	for _, user := range memory {
+		i := slices.IndexFunc(merged, func(u User) bool { return u.ID == user.ID })
+		switch {
+		case i < 0:
+			merged = append(merged, user)
+			pending = true
+		case user.UpdatedAt.After(merged[i].UpdatedAt):
+			merged[i] = user
+			pending = true
+		}
+	}
+	return merged, pending
+}